@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"golang.org/x/term"
+)
+
+// Case is a sample documented in form 'Molekulargenetische Untersuchung' and linked case IDs
+type Case struct {
+	SampleId            string   `json:"sampleId"`
+	SampleDate          string   `json:"sampleDate"`
+	PatientId           string   `json:"patientId"`
+	Probenmaterial      string   `json:"probenmaterial"`
+	Nukleinsaeure       string   `json:"nukleinsaeure"`
+	ArtDerSequenzierung string   `json:"artDerSequenzierung"`
+	Fallnummern         []string `json:"fallnummern"`
+}
+
+// CaseFilter contains filter values, empty values are not used to filter cases
+type CaseFilter struct {
+	PatientId  string
+	Fallnummer string
+	From       string
+	To         string
+}
+
+func (filter CaseFilter) matches(c Case) bool {
+	return (len(filter.PatientId) == 0 || c.PatientId == filter.PatientId) &&
+		(len(filter.Fallnummer) == 0 || slices.Contains(c.Fallnummern, filter.Fallnummer)) &&
+		(len(filter.From) == 0 || c.SampleDate >= filter.From) &&
+		(len(filter.To) == 0 || c.SampleDate <= filter.To)
+}
+
+func (repository *QueryRepository) FetchCases(filter CaseFilter) ([]Case, error) {
+	rows, err := repository.source.QueryRows("cases", filter.PatientId, filter.From, filter.To, filter.Fallnummer)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Case
+	for _, row := range rows {
+		fallnummern, err := repository.FetchFallnummern(row.Value("einsendenummer"))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, Case{
+			SampleId:            row.Value("einsendenummer"),
+			SampleDate:          row.Value("entnahmedatum"),
+			PatientId:           row.Value("patienten_id"),
+			Probenmaterial:      row.Value("probenmaterial"),
+			Nukleinsaeure:       row.Value("nukleinsaeure"),
+			ArtDerSequenzierung: row.Value("artdersequenzierung"),
+			Fallnummern:         fallnummern,
+		})
+	}
+	return result, nil
+}
+
+func (repository *FixtureRepository) FetchCases(filter CaseFilter) ([]Case, error) {
+	var result []Case
+	for _, c := range repository.Cases {
+		if filter.matches(c) {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+type CasesCmd struct {
+	PatientId  string `help:"Patienten-ID"`
+	Fallnummer string `help:"Fallnummer für das Modellvorhaben"`
+	From       string `help:"Entnahmedatum ab (JJJJ-MM-TT)"`
+	To         string `help:"Entnahmedatum bis (JJJJ-MM-TT)"`
+	List       bool   `help:"Einträge nur auflisten und keinen Export starten"`
+
+	Export ExportCmd `embed:""`
+}
+
+func (cmd *CasesCmd) Validate() error {
+	for _, date := range []string{cmd.From, cmd.To} {
+		if _, err := time.Parse(time.DateOnly, date); len(date) > 0 && err != nil {
+			return fmt.Errorf("invalid date '%s', expected format YYYY-MM-DD", date)
+		}
+	}
+	if len(cmd.PatientId) == 0 && len(cmd.Fallnummer) == 0 && len(cmd.From) == 0 && len(cmd.To) == 0 {
+		return fmt.Errorf("cases requires --patient-id, --fallnummer, --from or --to")
+	}
+	return nil
+}
+
+func (cmd *CasesCmd) Run(globals *Globals, repository Repository) error {
+	cases, err := repository.FetchCases(CaseFilter{
+		PatientId:  cmd.PatientId,
+		Fallnummer: cmd.Fallnummer,
+		From:       cmd.From,
+		To:         cmd.To,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(cases) == 0 {
+		fmt.Println("Keine Einträge im Formular 'Molekulargenetische Untersuchung' gefunden")
+		return nil
+	}
+
+	if cmd.List || !term.IsTerminal(int(os.Stdout.Fd())) {
+		printCases(cases)
+		return nil
+	}
+
+	var options []huh.Option[string]
+	for _, c := range cases {
+		options = append(options, huh.NewOption(caseLabel(c), c.SampleId))
+	}
+	err = huh.NewSelect[string]().
+		Title("Einsendenummer").
+		Description("Einträge im Formular 'Molekulargenetische Untersuchung'").
+		Options(options...).
+		Value(&globals.SampleId).
+		WithTheme(huh.ThemeBase16()).
+		Run()
+	if err != nil {
+		return err
+	}
+
+	return cmd.Export.Run(globals, repository)
+}
+
+func caseLabel(c Case) string {
+	return fmt.Sprintf("%s | %s | %s | %s %s | %s | Fallnummern: %s",
+		c.SampleId,
+		c.SampleDate,
+		c.PatientId,
+		c.Probenmaterial,
+		c.Nukleinsaeure,
+		c.ArtDerSequenzierung,
+		strings.Join(c.Fallnummern, ", "),
+	)
+}
+
+func printCases(cases []Case) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "Einsendenummer\tEntnahmedatum\tPatienten-ID\tMaterial\tNukleinsäure\tArt der Sequenzierung\tFallnummern")
+	for _, c := range cases {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			c.SampleId,
+			c.SampleDate,
+			c.PatientId,
+			c.Probenmaterial,
+			c.Nukleinsaeure,
+			c.ArtDerSequenzierung,
+			strings.Join(c.Fallnummern, ", "),
+		)
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFixtureRepositoryFetchCases(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   CaseFilter
+		expected []string
+	}{
+		{"patient", CaseFilter{PatientId: "P000001"}, []string{"H/2025/0001"}},
+		{"case", CaseFilter{Fallnummer: "FALL-2025-0001"}, []string{"H/2025/0001"}},
+		{"unknown case", CaseFilter{Fallnummer: "FALL-2025-9999"}, nil},
+		{"from", CaseFilter{From: "2025-04-01"}, []string{"H/2025/0002"}},
+		{"to", CaseFilter{To: "2025-03-12"}, []string{"H/2025/0001"}},
+		{"date range", CaseFilter{From: "2025-01-01", To: "2025-12-31"}, []string{"H/2025/0001", "H/2025/0002"}},
+		{"patient and date", CaseFilter{PatientId: "P000001", From: "2025-04-01"}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cases, err := fixtureRepository(t).FetchCases(test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sampleIds []string
+			for _, c := range cases {
+				sampleIds = append(sampleIds, c.SampleId)
+			}
+			if !slices.Equal(sampleIds, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, sampleIds)
+			}
+		})
+	}
+}
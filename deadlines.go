@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// DeadlineState of a case, ordered by severity
+type DeadlineState int
+
+const (
+	DeadlineOk DeadlineState = iota
+	DeadlineNear
+	// DeadlineUnknown is used for missing or invalid dates, the case might be overdue
+	DeadlineUnknown
+	DeadlineOverdue
+)
+
+func (state DeadlineState) String() string {
+	switch state {
+	case DeadlineNear:
+		return "near"
+	case DeadlineUnknown:
+		return "unknown"
+	case DeadlineOverdue:
+		return "overdue"
+	default:
+		return "ok"
+	}
+}
+
+func (state DeadlineState) MarshalText() ([]byte, error) {
+	return []byte(state.String()), nil
+}
+
+// Deadline contains elapsed days since sample date and consent of a case not yet exported
+type Deadline struct {
+	Fallnummer       string        `json:"fallnummer"`
+	SampleId         string        `json:"sampleId"`
+	SampleDate       string        `json:"sampleDate"`
+	ConsentDate      string        `json:"consentDate"`
+	DaysSinceSample  *int          `json:"daysSinceSample"`
+	DaysSinceConsent *int          `json:"daysSinceConsent"`
+	State            DeadlineState `json:"state"`
+}
+
+type DeadlinesCmd struct {
+	SampleDays  int  `help:"Frist in Tagen ab Entnahmedatum, 0 ohne Frist" default:"0"`
+	ConsentDays int  `help:"Frist in Tagen ab MV-Consent, 0 ohne Frist" default:"0"`
+	WarnDays    int  `help:"Hinweis auf Fristen, die in dieser Anzahl an Tagen ablaufen" default:"7"`
+	Json        bool `help:"Ausgabe als JSON" xor:"format"`
+	Csv         bool `help:"Ausgabe als CSV" xor:"format"`
+}
+
+func (cmd *DeadlinesCmd) Validate() error {
+	if cmd.SampleDays <= 0 && cmd.ConsentDays <= 0 {
+		return fmt.Errorf("deadlines requires --sample-days or --consent-days")
+	}
+	return nil
+}
+
+func (cmd *DeadlinesCmd) Run(globals *Globals, repository Repository) error {
+	sequencedCases, err := repository.FetchSequencedCases()
+	if err != nil {
+		return err
+	}
+	history, err := readHistory(globals.History)
+	if err != nil {
+		return fmt.Errorf("cannot read export history: %w", err)
+	}
+
+	deadlines := cmd.deadlines(sequencedCases, exportedCases(history), time.Now())
+
+	out := io.Writer(os.Stdout)
+	if len(globals.Filename) > 0 {
+		f, err := os.Create(globals.Filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	switch {
+	case cmd.Json:
+		j, _ := json.MarshalIndent(deadlines, "", "  ")
+		_, err := fmt.Fprintln(out, string(j))
+		return err
+	case cmd.Csv:
+		return writeDeadlinesCsv(out, deadlines)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "Fallnummer\tEinsendenummer\tEntnahmedatum\tTage\tMV-Consent\tTage\tStatus")
+	for _, deadline := range deadlines {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			deadline.Fallnummer,
+			deadline.SampleId,
+			deadline.SampleDate,
+			formatDays(deadline.DaysSinceSample),
+			deadline.ConsentDate,
+			formatDays(deadline.DaysSinceConsent),
+			deadline.State,
+		)
+	}
+	return w.Flush()
+}
+
+// deadlines returns the deadline state of all cases with MV consent not yet exported, most urgent first
+func (cmd *DeadlinesCmd) deadlines(sequencedCases []SequencedCase, exported map[[2]string]bool, now time.Time) []Deadline {
+	result := []Deadline{}
+	for _, c := range sequencedCases {
+		if c.Sequencing != metadata.Permit || exported[[2]string{c.Fallnummer, c.SampleId}] {
+			continue
+		}
+		deadline := Deadline{
+			Fallnummer:       c.Fallnummer,
+			SampleId:         c.SampleId,
+			SampleDate:       c.SampleDate,
+			ConsentDate:      c.ConsentDate,
+			DaysSinceSample:  daysSince(c.SampleDate, now),
+			DaysSinceConsent: daysSince(c.ConsentDate, now),
+		}
+		deadline.State = max(
+			cmd.state(deadline.DaysSinceSample, cmd.SampleDays),
+			cmd.state(deadline.DaysSinceConsent, cmd.ConsentDays),
+		)
+		result = append(result, deadline)
+	}
+
+	slices.SortStableFunc(result, func(a, b Deadline) int {
+		return int(b.State) - int(a.State)
+	})
+	return result
+}
+
+func (cmd *DeadlinesCmd) state(days *int, deadlineDays int) DeadlineState {
+	if deadlineDays <= 0 {
+		return DeadlineOk
+	}
+	if days == nil {
+		return DeadlineUnknown
+	}
+	if *days > deadlineDays {
+		return DeadlineOverdue
+	}
+	if deadlineDays-*days <= cmd.WarnDays {
+		return DeadlineNear
+	}
+	return DeadlineOk
+}
+
+// dateLayouts contains supported formats of date and datetime columns
+var dateLayouts = []string{time.DateOnly, time.DateTime, "2006-01-02T15:04:05", time.RFC3339}
+
+// parseDate returns the day of a date or datetime value
+func parseDate(date string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(date)); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// daysSince returns the elapsed days since the date or datetime, nil if missing or invalid
+func daysSince(date string, now time.Time) *int {
+	day, ok := parseDate(date)
+	if !ok {
+		return nil
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(today.Sub(day).Hours() / 24)
+	return &days
+}
+
+func formatDays(days *int) string {
+	if days == nil {
+		return ""
+	}
+	return strconv.Itoa(*days)
+}
+
+func writeDeadlinesCsv(out io.Writer, deadlines []Deadline) error {
+	w := csv.NewWriter(out)
+	_ = w.Write([]string{"fallnummer", "einsendenummer", "entnahmedatum", "tage_seit_entnahme", "consent_datum", "tage_seit_consent", "status"})
+	for _, deadline := range deadlines {
+		_ = w.Write([]string{
+			deadline.Fallnummer,
+			deadline.SampleId,
+			deadline.SampleDate,
+			formatDays(deadline.DaysSinceSample),
+			deadline.ConsentDate,
+			formatDays(deadline.DaysSinceConsent),
+			deadline.State.String(),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
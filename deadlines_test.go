@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+func TestDaysSince(t *testing.T) {
+	now := time.Date(2025, 4, 1, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		date     string
+		expected *int
+	}{
+		{"2025-03-12", intPtr(20)},
+		{"2025-03-12 23:59:59", intPtr(20)},
+		{"2025-03-12T08:00:00", intPtr(20)},
+		{"2025-03-12T08:00:00+01:00", intPtr(20)},
+		{"12.03.2025", nil},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.date, func(t *testing.T) {
+			actual := daysSince(test.date, now)
+			if formatDays(actual) != formatDays(test.expected) {
+				t.Errorf("expected '%s', got '%s'", formatDays(test.expected), formatDays(actual))
+			}
+		})
+	}
+}
+
+func TestDeadlinesWithInvalidDate(t *testing.T) {
+	cmd := DeadlinesCmd{SampleDays: 30, ConsentDays: 30, WarnDays: 7}
+	now := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	deadlines := cmd.deadlines([]SequencedCase{
+		{Fallnummer: "F1", SampleId: "S1", SampleDate: "2025-03-20", ConsentDate: "2025-03-20", Sequencing: metadata.Permit},
+		{Fallnummer: "F2", SampleId: "S2", SampleDate: "2025-03-20", ConsentDate: "20.03.2025", Sequencing: metadata.Permit},
+		{Fallnummer: "F3", SampleId: "S3", SampleDate: "2025-01-02 10:00:00", ConsentDate: "invalid", Sequencing: metadata.Permit},
+	}, map[[2]string]bool{}, now)
+
+	expected := map[string]DeadlineState{"F1": DeadlineOk, "F2": DeadlineUnknown, "F3": DeadlineOverdue}
+	for _, deadline := range deadlines {
+		if deadline.State != expected[deadline.Fallnummer] {
+			t.Errorf("expected '%s' for case '%s', got '%s'", expected[deadline.Fallnummer], deadline.Fallnummer, deadline.State)
+		}
+	}
+	if deadlines[0].Fallnummer != "F3" || deadlines[1].Fallnummer != "F2" {
+		t.Error("expected most urgent cases first")
+	}
+}
+
+func intPtr(value int) *int {
+	return &value
+}
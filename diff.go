@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+type DiffCmd struct {
+	Metadata string `arg:"" help:"Zuvor exportierte Metadaten-Datei" type:"existingfile"`
+	Json     bool   `help:"Ausgabe der Unterschiede als JSON"`
+}
+
+func (cmd *DiffCmd) Run(globals *Globals, repository Repository) error {
+	if len(globals.SampleId) == 0 {
+		return fmt.Errorf("diff requires --sample-id")
+	}
+
+	existing, err := readMetadataFile(cmd.Metadata)
+	if err != nil {
+		return err
+	}
+
+	current, err := repository.FetchMetadata(globals.SampleId, existing.Submission.LocalCaseID)
+	if err != nil {
+		return fmt.Errorf("cannot fetch metadata: %w", err)
+	}
+
+	differences := diffMetadata(existing, current)
+
+	if cmd.Json {
+		j, _ := json.MarshalIndent(differences, "", "  ")
+		fmt.Println(string(j))
+		return nil
+	}
+
+	if len(differences) == 0 {
+		fmt.Printf("\033[32m✅ Keine Änderungen in Onkostar seit dem Export in '%s'.\033[0m\n", cmd.Metadata)
+		return nil
+	}
+	fmt.Printf("\033[33m⚠ %d Änderungen in Onkostar seit dem Export in '%s':\033[0m\n", len(differences), cmd.Metadata)
+	for _, difference := range differences {
+		fmt.Printf("  %s\n", difference)
+	}
+	return nil
+}
+
+// diffMetadata compares all fields sourced from Onkostar and returns a list of changed values.
+// Fields usually overwritten by profiles are not compared.
+func diffMetadata(existing *metadata.Metadata, current *metadata.Metadata) []Conflict {
+	differences := []Conflict{}
+
+	diffField(&differences, "submission.coverageType", existing.Submission.CoverageType, current.Submission.CoverageType)
+
+	var donor, currentDonor metadata.Donor
+	if len(existing.Donors) > 0 {
+		donor = existing.Donors[0]
+	}
+	if len(current.Donors) > 0 {
+		currentDonor = current.Donors[0]
+	}
+
+	diffField(&differences, "donors[0].donorPseudonym", donor.DonorPseudonym, currentDonor.DonorPseudonym)
+	diffField(&differences, "donors[0].gender", donor.Gender, currentDonor.Gender)
+	diffField(&differences, "donors[0].mvConsent.presentationDate", donor.MvConsent.PresentationDate, currentDonor.MvConsent.PresentationDate)
+	diffField(&differences, "donors[0].mvConsent.version", donor.MvConsent.Version, currentDonor.MvConsent.Version)
+	for _, domain := range []metadata.Domain{metadata.MvSequencing, metadata.ReIdentification, metadata.CaseIdentification} {
+		diffField(&differences, fmt.Sprintf("donors[0].mvConsent.scope[%s]", domain), findScope(donor.MvConsent, domain), findScope(currentDonor.MvConsent, domain))
+	}
+
+	pairs := pairLabData(donor.LabData, currentDonor.LabData)
+	paired := make([]bool, len(donor.LabData))
+	added := len(donor.LabData)
+	for idx, currentLabData := range currentDonor.LabData {
+		if pairs[idx] < 0 {
+			diffLabData(&differences, fmt.Sprintf("donors[0].labData[%d]", added), metadata.LabDatum{}, currentLabData)
+			added++
+			continue
+		}
+		paired[pairs[idx]] = true
+		diffLabData(&differences, fmt.Sprintf("donors[0].labData[%d]", pairs[idx]), donor.LabData[pairs[idx]], currentLabData)
+	}
+	for idx, labData := range donor.LabData {
+		if !paired[idx] {
+			diffLabData(&differences, fmt.Sprintf("donors[0].labData[%d]", idx), labData, metadata.LabDatum{})
+		}
+	}
+
+	return differences
+}
+
+func diffLabData(differences *[]Conflict, path string, labData metadata.LabDatum, currentLabData metadata.LabDatum) {
+	diffField(differences, path+".sampleDate", labData.SampleDate, currentLabData.SampleDate)
+	diffField(differences, path+".sampleConservation", labData.SampleConservation, currentLabData.SampleConservation)
+	diffField(differences, path+".tissueTypeId", labData.TissueTypeID, currentLabData.TissueTypeID)
+	diffField(differences, path+".tumorCellCount", tumorCellCounts(labData), tumorCellCounts(currentLabData))
+	diffField(differences, path+".sequenceData.referenceGenome", referenceGenome(labData), referenceGenome(currentLabData))
+}
+
+func diffField[T any](differences *[]Conflict, path string, existing T, current T) {
+	if reflect.DeepEqual(existing, current) {
+		return
+	}
+	*differences = append(*differences, Conflict{Path: path, Existing: existing, Current: current})
+}
+
+func findScope(consent metadata.MvConsent, domain metadata.Domain) *metadata.Scope {
+	for _, scope := range consent.Scope {
+		if scope.Domain == domain {
+			return &scope
+		}
+	}
+	return nil
+}
+
+// tumorCellCounts returns the counts only, since methods are usually overwritten by profiles.
+// Bioinformatic estimates are not sourced from Onkostar.
+func tumorCellCounts(labData metadata.LabDatum) []float64 {
+	result := []float64{}
+	for _, tumorCellCount := range labData.TumorCellCount {
+		if tumorCellCount.Method == metadata.Bioinformatics {
+			continue
+		}
+		result = append(result, tumorCellCount.Count)
+	}
+	return result
+}
+
+func referenceGenome(labData metadata.LabDatum) metadata.ReferenceGenome {
+	if labData.SequenceData == nil {
+		return ""
+	}
+	return labData.SequenceData.ReferenceGenome
+}
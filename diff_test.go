@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+func TestDiffMetadataPairsLabDataBySequenceType(t *testing.T) {
+	existing := &metadata.Metadata{
+		Donors: []metadata.Donor{{
+			LabData: []metadata.LabDatum{
+				{SequenceType: metadata.Dna, SampleDate: "2025-01-01"},
+				{SequenceType: metadata.Rna, SampleDate: "2025-01-01"},
+			},
+		}},
+	}
+
+	tests := []struct {
+		name     string
+		current  []metadata.LabDatum
+		expected []string
+	}{
+		{
+			name: "reverse order without changes",
+			current: []metadata.LabDatum{
+				{SequenceType: metadata.Rna, SampleDate: "2025-01-01"},
+				{SequenceType: metadata.Dna, SampleDate: "2025-01-01"},
+			},
+			expected: []string{},
+		},
+		{
+			name: "changed RNA sample date",
+			current: []metadata.LabDatum{
+				{SequenceType: metadata.Rna, SampleDate: "2025-01-02"},
+				{SequenceType: metadata.Dna, SampleDate: "2025-01-01"},
+			},
+			expected: []string{"donors[0].labData[1].sampleDate"},
+		},
+		{
+			name: "removed RNA lab datum",
+			current: []metadata.LabDatum{
+				{SequenceType: metadata.Dna, SampleDate: "2025-01-01"},
+			},
+			expected: []string{"donors[0].labData[1].sampleDate"},
+		},
+		{
+			name: "added DNA lab datum",
+			current: []metadata.LabDatum{
+				{SequenceType: metadata.Dna, SampleDate: "2025-01-01"},
+				{SequenceType: metadata.Dna, SampleDate: "2025-01-03"},
+				{SequenceType: metadata.Rna, SampleDate: "2025-01-01"},
+			},
+			expected: []string{"donors[0].labData[2].sampleDate"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			current := &metadata.Metadata{Donors: []metadata.Donor{{LabData: test.current}}}
+			differences := diffMetadata(existing, current)
+			if len(differences) != len(test.expected) {
+				t.Fatalf("expected %d differences, got %v", len(test.expected), differences)
+			}
+			for idx, path := range test.expected {
+				if differences[idx].Path != path {
+					t.Errorf("expected difference in '%s', got '%s'", path, differences[idx].Path)
+				}
+			}
+		})
+	}
+}
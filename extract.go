@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Extract contains query results to be used without database connection
+type Extract struct {
+	Created string `json:"created"`
+	// Queries contains the SQL used to create this extract by query name
+	Queries map[string]string `json:"queries"`
+	// Results contains the result rows by query name and parameters, multiple parameters are separated by ','
+	Results map[string]map[string][]Row `json:"results"`
+}
+
+// ExtractSource uses results of an extract as created by command 'extract' or a directory with CSV files
+type ExtractSource struct {
+	extract Extract
+}
+
+// NewExtractSource reads an extract file or a directory with CSV files
+func NewExtractSource(path string) (*ExtractSource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var extract *Extract
+	if info.IsDir() {
+		extract, err = readCsvExtract(path)
+	} else {
+		extract, err = readJsonExtract(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExtractSource{extract: *extract}, nil
+}
+
+// QueryRows returns the rows of the extract. Missing results are an error, since the extract does not contain
+// any data for this query and parameters instead of an empty result.
+func (source *ExtractSource) QueryRows(name string, params ...string) ([]Row, error) {
+	key := strings.Join(params, ",")
+	if rows, ok := source.extract.Results[name][key]; ok {
+		return rows, nil
+	}
+	return nil, fmt.Errorf("extract contains no results of query '%s' for parameters '%s'", name, key)
+}
+
+func readJsonExtract(filename string) (*Extract, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var result Extract
+	if err := json.Unmarshal(content, &result); err != nil {
+		return nil, fmt.Errorf("cannot read extract '%s': %w", filename, err)
+	}
+	return &result, nil
+}
+
+// readCsvExtract reads a CSV file for each query. The first column 'parameter' contains the query parameters
+// separated by ',', all other columns the result columns. Empty values are used as NULL.
+func readCsvExtract(dir string) (*Extract, error) {
+	result := Extract{
+		Queries: map[string]string{},
+		Results: map[string]map[string][]Row{},
+	}
+
+	for _, name := range queryNames {
+		f, err := os.Open(filepath.Join(dir, name+".csv"))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		reader := csv.NewReader(f)
+		header, err := reader.Read()
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("cannot read extract '%s.csv': %w", name, err)
+		}
+
+		result.Results[name] = map[string][]Row{}
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				_ = f.Close()
+				return nil, fmt.Errorf("cannot read extract '%s.csv': %w", name, err)
+			}
+
+			row := Row{}
+			for idx, column := range header[1:] {
+				if value := record[idx+1]; len(value) > 0 {
+					row[strings.ToLower(column)] = &value
+				} else {
+					row[strings.ToLower(column)] = nil
+				}
+			}
+			result.Results[name][record[0]] = append(result.Results[name][record[0]], row)
+		}
+		_ = f.Close()
+	}
+
+	return &result, nil
+}
+
+type ExtractCmd struct {
+	SampleIds []string `arg:"" optional:"" help:"Weitere Einsendenummern zusätzlich zu --sample-id"`
+}
+
+func (cmd *ExtractCmd) Run(globals *Globals, repository Repository) error {
+	queryRepository, ok := repository.(*QueryRepository)
+	if !ok {
+		return fmt.Errorf("extract cannot be created from fixture")
+	}
+
+	sampleIds := cmd.SampleIds
+	if len(globals.SampleId) > 0 {
+		sampleIds = append([]string{globals.SampleId}, sampleIds...)
+	}
+	if len(sampleIds) == 0 {
+		return fmt.Errorf("extract requires --sample-id")
+	}
+
+	extract := Extract{
+		Created: time.Now().Format(time.RFC3339),
+		Queries: map[string]string{},
+		Results: map[string]map[string][]Row{},
+	}
+	for _, name := range queryNames {
+		query, err := loadQuery(globals.QueryDir, name)
+		if err != nil {
+			return err
+		}
+		extract.Queries[name] = query
+		extract.Results[name] = map[string][]Row{}
+	}
+
+	addRows := func(name string, params ...string) ([]Row, error) {
+		key := strings.Join(params, ",")
+		if rows, ok := extract.Results[name][key]; ok {
+			return rows, nil
+		}
+		rows, err := queryRepository.source.QueryRows(name, params...)
+		if err != nil {
+			return nil, err
+		}
+		extract.Results[name][key] = rows
+		return rows, nil
+	}
+	addCases := func(patientId string, fallnummer string) error {
+		cases, err := addRows("cases", patientId, "", "", fallnummer)
+		if err != nil {
+			return err
+		}
+		// Case IDs are fetched for each sample found
+		for _, c := range cases {
+			if _, err := addRows("fallnummern", c.Value("einsendenummer")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, sampleId := range sampleIds {
+		rows, err := addRows("metadata", sampleId)
+		if err != nil {
+			return err
+		}
+		if _, err := addRows("topography", sampleId); err != nil {
+			log.Printf("Warning: Cannot fetch ICD-O-3 topography of sample '%s': %s\n", sampleId, err.Error())
+		}
+		fallnummern, err := addRows("fallnummern", sampleId)
+		if err != nil {
+			return err
+		}
+		for _, fallnummer := range fallnummern {
+			if _, err := addRows("mvconsent", fallnummer.Value("fallnummer")); err != nil {
+				return err
+			}
+			if err := addCases("", fallnummer.Value("fallnummer")); err != nil {
+				return err
+			}
+		}
+		// The default queries use the patient ID as donor pseudonym
+		for _, row := range rows {
+			if patientId := row.Value("donors_items_donorpseudonym"); len(patientId) > 0 {
+				if err := addCases(patientId, ""); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if _, err := addRows("sequencedcases"); err != nil {
+		return err
+	}
+
+	j, _ := json.MarshalIndent(extract, "", "  ")
+	if len(globals.Filename) == 0 {
+		fmt.Println(string(j))
+		return nil
+	}
+	if err := os.WriteFile(globals.Filename, j, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("\033[32m✅ Extrakt wurde in die Datei '%s' geschrieben.\033[0m\n", globals.Filename)
+	return nil
+}
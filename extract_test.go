@@ -0,0 +1,72 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// extractRepository creates an extract of the Onkostar test database and uses it as data source
+func extractRepository(t *testing.T) *QueryRepository {
+	t.Helper()
+	globals := &Globals{
+		SampleId: "H/2025/0001",
+		Filename: filepath.Join(t.TempDir(), "extract.json"),
+	}
+	cmd := ExtractCmd{}
+	if err := cmd.Run(globals, onkostarRepository(t, "")); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := NewExtractSource(globals.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mappings, _ := ReadMappings("")
+	tissueMapping, _ := ReadTissueMapping("")
+	return NewQueryRepository(source, mappings, tissueMapping)
+}
+
+func TestExtractReproducesMetadata(t *testing.T) {
+	data, err := extractRepository(t).FetchMetadata("H/2025/0001", "FALL-2025-0001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Same metadata as fetched from database
+	assertGolden(t, "metadata-onkostar", data)
+}
+
+func TestExtractContainsCases(t *testing.T) {
+	repository := extractRepository(t)
+
+	for _, filter := range []CaseFilter{{PatientId: "P000001"}, {Fallnummer: "FALL-2025-0001"}} {
+		cases, err := repository.FetchCases(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cases) == 0 {
+			t.Errorf("expected cases for filter %v", filter)
+		}
+	}
+
+	sequencedCases, err := repository.FetchSequencedCases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sequencedCases) != 1 {
+		t.Errorf("expected one sequenced case, got %d", len(sequencedCases))
+	}
+}
+
+func TestExtractWithoutResults(t *testing.T) {
+	repository := extractRepository(t)
+
+	if _, err := repository.FetchMetadata("H/2025/9999", ""); err == nil {
+		t.Error("expected error for sample not contained in extract")
+	}
+	if _, err := repository.FetchMetadata("H/2025/0001", "FALL-2025-9999"); err == nil {
+		t.Error("expected error for MV consent not contained in extract")
+	}
+	if _, err := repository.FetchCases(CaseFilter{From: "2025-01-01"}); err == nil {
+		t.Error("expected error for cases not contained in extract")
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// Kinds of exports in the history
+const (
+	// HistorySubmission is a submission directory created for grz-cli
+	HistorySubmission = "submission"
+	// HistoryTemplate is a metadata file written for manual completion
+	HistoryTemplate = "template"
+	// HistoryDownload is a metadata file downloaded in the web frontend
+	HistoryDownload = "download"
+)
+
+// HistoryEntry describes a single export of metadata
+type HistoryEntry struct {
+	Date             string `json:"date"`
+	Kind             string `json:"kind"`
+	SampleId         string `json:"sampleId"`
+	Fallnummer       string `json:"fallnummer"`
+	Ik               string `json:"ik"`
+	Profile          string `json:"profile"`
+	Grz              string `json:"grz"`
+	Kdk              string `json:"kdk"`
+	SubmissionType   string `json:"submissionType"`
+	GenomicStudyType string `json:"genomicStudyType"`
+	// LibraryTypes contains the library type of each lab datum
+	LibraryTypes []string `json:"libraryTypes"`
+	CoverageType string   `json:"coverageType"`
+	Output       string   `json:"output"`
+}
+
+func newHistoryEntry(kind string, request MetadataRequest, data *metadata.Metadata, output string) HistoryEntry {
+	entry := HistoryEntry{
+		Date:             time.Now().Format(time.RFC3339),
+		Kind:             kind,
+		SampleId:         request.SampleId,
+		Fallnummer:       data.Submission.LocalCaseID,
+		Ik:               request.Ik,
+		Profile:          request.Profile,
+		Grz:              data.Submission.GenomicDataCenterID,
+		Kdk:              data.Submission.ClinicalDataNodeID,
+		SubmissionType:   string(data.Submission.SubmissionType),
+		GenomicStudyType: string(data.Submission.GenomicStudyType),
+		LibraryTypes:     []string{},
+		CoverageType:     string(data.Submission.CoverageType),
+		Output:           output,
+	}
+	for _, donor := range data.Donors {
+		for _, labData := range donor.LabData {
+			entry.LibraryTypes = append(entry.LibraryTypes, string(labData.LibraryType))
+		}
+	}
+	return entry
+}
+
+// appendHistory adds the entry as JSON line to the history file
+func appendHistory(filename string, entry HistoryEntry) error {
+	if len(filename) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	j, _ := json.Marshal(entry)
+	if _, err := f.Write(append(j, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readHistory returns all entries of the history file. A missing file results in an empty history.
+func readHistory(filename string) ([]HistoryEntry, error) {
+	f, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return []HistoryEntry{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result := []HistoryEntry{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, err
+		}
+		result = append(result, entry)
+	}
+	return result, scanner.Err()
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// isLongRead returns true for long-read library types like 'wgs_lr'
+func isLongRead(libraryType metadata.LibraryType) bool {
+	return strings.HasSuffix(string(libraryType), "_lr")
+}
+
+// ReadLengths contains read length statistics of a sequence file
+type ReadLengths struct {
+	Reads int64
+	Bases int64
+	Max   int64
+}
+
+// Mean returns the rounded average read length
+func (readLengths ReadLengths) Mean() int64 {
+	if readLengths.Reads == 0 {
+		return 0
+	}
+	return int64(math.Round(float64(readLengths.Bases) / float64(readLengths.Reads)))
+}
+
+// readFastqLengths returns read length statistics of a plain or compressed FASTQ file
+func readFastqLengths(filename string) (*ReadLengths, error) {
+	f, err := openFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result := ReadLengths{}
+	scanner := bufio.NewScanner(f)
+	// Long reads might exceed 1 Mb
+	scanner.Buffer(make([]byte, 0, 1024*1024), 64*1024*1024)
+	for line := 0; scanner.Scan(); line++ {
+		if line%4 != 1 {
+			continue
+		}
+		length := int64(len(scanner.Bytes()))
+		result.Reads++
+		result.Bases += length
+		result.Max = max(result.Max, length)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read FASTQ file '%s': %w", filename, err)
+	}
+	return &result, nil
+}
+
+// readBamLengths returns read length statistics of primary alignments in a BAM file, e.g. unaligned BAM of ONT or PacBio
+func readBamLengths(filename string) (*ReadLengths, error) {
+	f, err := openFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+
+	var magic [4]byte
+	var size int32
+	if err := binary.Read(reader, binary.LittleEndian, &magic); err != nil || string(magic[:]) != "BAM\x01" {
+		return nil, fmt.Errorf("file '%s' is not a BAM file", filename)
+	}
+	// Skip header text and references
+	if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+		return nil, fmt.Errorf("cannot read BAM file '%s': %w", filename, err)
+	}
+	if _, err := reader.Discard(int(size)); err != nil {
+		return nil, fmt.Errorf("cannot read BAM file '%s': %w", filename, err)
+	}
+	var references int32
+	if err := binary.Read(reader, binary.LittleEndian, &references); err != nil {
+		return nil, fmt.Errorf("cannot read BAM file '%s': %w", filename, err)
+	}
+	for range references {
+		if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+			return nil, fmt.Errorf("cannot read BAM file '%s': %w", filename, err)
+		}
+		if _, err := reader.Discard(int(size) + 4); err != nil {
+			return nil, fmt.Errorf("cannot read BAM file '%s': %w", filename, err)
+		}
+	}
+
+	result := ReadLengths{}
+	record := make([]byte, 20)
+	for {
+		var blockSize int32
+		if err := binary.Read(reader, binary.LittleEndian, &blockSize); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("cannot read BAM file '%s': %w", filename, err)
+		}
+		if _, err := io.ReadFull(reader, record); err != nil {
+			return nil, fmt.Errorf("cannot read BAM file '%s': %w", filename, err)
+		}
+		if _, err := reader.Discard(int(blockSize) - len(record)); err != nil {
+			return nil, fmt.Errorf("cannot read BAM file '%s': %w", filename, err)
+		}
+
+		// Secondary and supplementary alignments do not contain additional reads
+		flag := binary.LittleEndian.Uint16(record[14:16])
+		if flag&0x900 != 0 {
+			continue
+		}
+		length := int64(binary.LittleEndian.Uint32(record[16:20]))
+		result.Reads++
+		result.Bases += length
+		result.Max = max(result.Max, length)
+	}
+	return &result, nil
+}
+
+// applyReadLengths sets the read length of FASTQ and BAM files in filesDir without read length.
+// Long-read data uses the rounded average read length, short-read data the maximum read length.
+func applyReadLengths(data *metadata.Metadata, filesDir string) {
+	if len(data.Donors) == 0 {
+		return
+	}
+	for _, labData := range data.Donors[0].LabData {
+		if labData.SequenceData == nil {
+			continue
+		}
+		for idx := range labData.SequenceData.Files {
+			file := &labData.SequenceData.Files[idx]
+			if file.ReadLength != nil {
+				continue
+			}
+
+			var readLengths *ReadLengths
+			var err error
+			switch file.FileType {
+			case metadata.Fastq:
+				readLengths, err = readFastqLengths(filepath.Join(filesDir, file.FilePath))
+			case metadata.BAM:
+				readLengths, err = readBamLengths(filepath.Join(filesDir, file.FilePath))
+			default:
+				continue
+			}
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			} else if err != nil {
+				log.Printf("Warning: Cannot determine read length: %s\n", err.Error())
+				continue
+			}
+
+			readLength := readLengths.Max
+			if isLongRead(labData.LibraryType) {
+				readLength = readLengths.Mean()
+			}
+			file.ReadLength = &readLength
+		}
+	}
+}
+
+// checkLongReadLabData returns messages for long-read lab data using paired-end layout or read order
+func checkLongReadLabData(data *metadata.Metadata) []string {
+	var result []string
+	if len(data.Donors) == 0 {
+		return result
+	}
+	for idx, labData := range data.Donors[0].LabData {
+		if !isLongRead(labData.LibraryType) {
+			continue
+		}
+		if labData.SequencingLayout == metadata.PairedEnd {
+			result = append(result, fmt.Sprintf("labData[%d]: long-read library type '%s' cannot use sequencing layout '%s'", idx, labData.LibraryType, labData.SequencingLayout))
+		}
+		if labData.SequenceData == nil {
+			continue
+		}
+		for _, file := range labData.SequenceData.Files {
+			if file.ReadOrder != nil {
+				result = append(result, fmt.Sprintf("labData[%d]: long-read file '%s' cannot use read order", idx, file.FilePath))
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+type bamRecord struct {
+	name   string
+	flag   uint16
+	length int
+}
+
+// bamContent returns uncompressed BAM content with one reference and the given records
+func bamContent(records []bamRecord) []byte {
+	var content bytes.Buffer
+	header := "@HD\tVN:1.6\tSO:unknown\n"
+	content.WriteString("BAM\x01")
+	_ = binary.Write(&content, binary.LittleEndian, int32(len(header)))
+	content.WriteString(header)
+	_ = binary.Write(&content, binary.LittleEndian, int32(1))
+	_ = binary.Write(&content, binary.LittleEndian, int32(5))
+	content.WriteString("chr1\x00")
+	_ = binary.Write(&content, binary.LittleEndian, int32(248956422))
+
+	for _, record := range records {
+		name := record.name + "\x00"
+		seq := (record.length + 1) / 2
+		_ = binary.Write(&content, binary.LittleEndian, int32(32+len(name)+seq+record.length))
+		_ = binary.Write(&content, binary.LittleEndian, int32(-1))
+		_ = binary.Write(&content, binary.LittleEndian, int32(-1))
+		content.WriteByte(byte(len(name)))
+		content.WriteByte(255)
+		_ = binary.Write(&content, binary.LittleEndian, uint16(4680))
+		_ = binary.Write(&content, binary.LittleEndian, uint16(0))
+		_ = binary.Write(&content, binary.LittleEndian, record.flag)
+		_ = binary.Write(&content, binary.LittleEndian, int32(record.length))
+		_ = binary.Write(&content, binary.LittleEndian, int32(-1))
+		_ = binary.Write(&content, binary.LittleEndian, int32(-1))
+		_ = binary.Write(&content, binary.LittleEndian, int32(0))
+		content.WriteString(name)
+		content.Write(make([]byte, seq))
+		content.Write(bytes.Repeat([]byte{0xff}, record.length))
+	}
+	return content.Bytes()
+}
+
+// bgzf compresses content into BGZF blocks of at most blockSize uncompressed bytes followed by the EOF block
+func bgzf(t *testing.T, content []byte, blockSize int) []byte {
+	t.Helper()
+	var result bytes.Buffer
+	for {
+		var block bytes.Buffer
+		gz, _ := gzip.NewWriterLevel(&block, gzip.BestCompression)
+		gz.Extra = []byte{'B', 'C', 2, 0, 0, 0}
+		size := min(blockSize, len(content))
+		if _, err := gz.Write(content[:size]); err != nil {
+			t.Fatal(err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+		// BSIZE is the total block size minus 1
+		binary.LittleEndian.PutUint16(block.Bytes()[16:18], uint16(block.Len()-1))
+		result.Write(block.Bytes())
+		if size == 0 {
+			break
+		}
+		content = content[size:]
+	}
+	return result.Bytes()
+}
+
+func TestReadBamLengths(t *testing.T) {
+	tests := []struct {
+		name     string
+		records  []bamRecord
+		expected ReadLengths
+	}{
+		{
+			name:     "unaligned reads",
+			records:  []bamRecord{{"read1", 0x4, 1000}, {"read2", 0x4, 3001}},
+			expected: ReadLengths{Reads: 2, Bases: 4001, Max: 3001},
+		},
+		{
+			name: "secondary and supplementary alignments",
+			records: []bamRecord{
+				{"read1", 0x0, 2000},
+				{"read1", 0x100, 2000},
+				{"read1", 0x800, 500},
+				{"read2", 0x10, 1000},
+				{"read2", 0x910, 1000},
+			},
+			expected: ReadLengths{Reads: 2, Bases: 3000, Max: 2000},
+		},
+		{
+			name:     "no reads",
+			records:  []bamRecord{},
+			expected: ReadLengths{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "sample.bam")
+			// Small blocks to split records across BGZF blocks
+			if err := os.WriteFile(filename, bgzf(t, bamContent(test.records), 1000), 0644); err != nil {
+				t.Fatal(err)
+			}
+			actual, err := readBamLengths(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *actual != test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, *actual)
+			}
+		})
+	}
+}
+
+func TestReadBamLengthsWithInvalidFile(t *testing.T) {
+	content := bamContent([]bamRecord{{"read1", 0x0, 1000}})
+	tests := []struct {
+		name    string
+		content []byte
+		message string
+	}{
+		{name: "no BAM file", content: []byte("@read1\nACGT\n+\nIIII\n"), message: "is not a BAM file"},
+		{name: "truncated record", content: bgzf(t, content[:len(content)-10], 1000), message: "cannot read BAM file"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "sample.bam")
+			if err := os.WriteFile(filename, test.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := readBamLengths(filename); err == nil || !strings.Contains(err.Error(), test.message) {
+				t.Errorf("expected error '%s', got %v", test.message, err)
+			}
+		})
+	}
+}
+
+func TestReadFastqLengths(t *testing.T) {
+	longRead := strings.Repeat("A", 2*1024*1024)
+	tests := []struct {
+		name     string
+		content  string
+		gzipped  bool
+		expected ReadLengths
+	}{
+		{
+			name:     "plain",
+			content:  "@read1\nACGT\n+\nIIII\n@read2\nACGTACG\n+\nIIIIIII\n",
+			expected: ReadLengths{Reads: 2, Bases: 11, Max: 7},
+		},
+		{
+			name:     "gzipped",
+			content:  "@read1\nACGT\n+\nIIII\n@read2\nACGTACG\n+\nIIIIIII\n",
+			gzipped:  true,
+			expected: ReadLengths{Reads: 2, Bases: 11, Max: 7},
+		},
+		{
+			name:     "quality line starting with '@'",
+			content:  "@read1\nACGT\n+\n@III\n@read2\nAC\n+\n@I\n",
+			expected: ReadLengths{Reads: 2, Bases: 6, Max: 4},
+		},
+		{
+			name:     "long read exceeding default buffer",
+			content:  "@read1\n" + longRead + "\n+\n" + longRead + "\n@read2\nACGT\n+\nIIII\n",
+			gzipped:  true,
+			expected: ReadLengths{Reads: 2, Bases: int64(len(longRead)) + 4, Max: int64(len(longRead))},
+		},
+		{
+			name:     "empty",
+			expected: ReadLengths{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			content := []byte(test.content)
+			if test.gzipped {
+				var compressed bytes.Buffer
+				gz := gzip.NewWriter(&compressed)
+				_, _ = gz.Write(content)
+				_ = gz.Close()
+				content = compressed.Bytes()
+			}
+			filename := filepath.Join(t.TempDir(), "sample.fastq.gz")
+			if err := os.WriteFile(filename, content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			actual, err := readFastqLengths(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *actual != test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, *actual)
+			}
+		})
+	}
+}
+
+func TestApplyReadLengths(t *testing.T) {
+	filesDir := t.TempDir()
+	writeTestFile(t, filesDir, "short.fastq", "@read1\nACGT\n+\nIIII\n@read2\nACGTACGTAC\n+\nIIIIIIIIII\n")
+	if err := os.WriteFile(filepath.Join(filesDir, "long.bam"), bgzf(t, bamContent([]bamRecord{{"read1", 0x4, 1000}, {"read2", 0x4, 2001}}), 65280), 0644); err != nil {
+		t.Fatal(err)
+	}
+	existing := int64(151)
+	data := &metadata.Metadata{
+		Donors: []metadata.Donor{
+			{
+				LabData: []metadata.LabDatum{
+					{
+						LibraryType: metadata.Panel,
+						SequenceData: &metadata.SequenceData{
+							Files: []metadata.File{
+								{FilePath: "short.fastq", FileType: metadata.Fastq},
+								{FilePath: "missing.fastq", FileType: metadata.Fastq},
+								{FilePath: "existing.fastq", FileType: metadata.Fastq, ReadLength: &existing},
+							},
+						},
+					},
+					{
+						LibraryType: metadata.WgsLr,
+						SequenceData: &metadata.SequenceData{
+							Files: []metadata.File{{FilePath: "long.bam", FileType: metadata.BAM}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	applyReadLengths(data, filesDir)
+
+	files := data.Donors[0].LabData[0].SequenceData.Files
+	if files[0].ReadLength == nil || *files[0].ReadLength != 10 {
+		t.Errorf("expected maximum read length 10 for short reads, got %v", files[0].ReadLength)
+	}
+	if files[1].ReadLength != nil {
+		t.Errorf("expected no read length for missing file, got %d", *files[1].ReadLength)
+	}
+	if *files[2].ReadLength != 151 {
+		t.Errorf("expected existing read length to be kept, got %d", *files[2].ReadLength)
+	}
+	if longRead := data.Donors[0].LabData[1].SequenceData.Files[0].ReadLength; longRead == nil || *longRead != 1501 {
+		t.Errorf("expected rounded mean read length 1501 for long reads, got %v", longRead)
+	}
+}
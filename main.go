@@ -22,10 +22,12 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
-	"strconv"
+	"path/filepath"
+	"strings"
 
 	"github.com/alecthomas/kong"
 	"github.com/charmbracelet/huh"
@@ -36,22 +38,227 @@ import (
 var (
 	cli     *CLI
 	context *kong.Context
-	db      *sql.DB
 )
 
 type Globals struct {
-	User     string `short:"U" help:"Database username" required:"NA"`
-	Password string `short:"P" help:"Database password"`
-	Host     string `short:"H" help:"Database host" default:"localhost"`
-	Port     int    `help:"Database port" default:"3306"`
-	Ssl      string `help:"SSL-Verbindung ('true', 'false', 'skip-verify', 'preferred')" default:"false"`
-	Database string `short:"D" help:"Database name" default:"onkostar"`
-	SampleId string `help:"Einsendenummer"`
-	Filename string `help:"Ausgabedatei"`
+	User        string `short:"U" help:"Database username" required:"NA"`
+	Password    string `short:"P" help:"Database password"`
+	Host        string `short:"H" help:"Database host" default:"localhost"`
+	Port        int    `help:"Database port" default:"3306"`
+	Ssl         string `help:"SSL-Verbindung ('true', 'false', 'skip-verify', 'preferred')" default:"false"`
+	Database    string `short:"D" help:"Database name" default:"onkostar"`
+	SampleId    string `help:"Einsendenummer"`
+	Filename    string `help:"Ausgabedatei"`
+	QueryDir    string `help:"Verzeichnis mit angepassten SQL-Abfragen" type:"existingdir"`
+	Mappings    string `help:"Datei mit angepassten Zuordnungen von Onkostar-Codes" type:"existingfile"`
+	Tissues     string `help:"Datei mit angepasster Zuordnung von Probenmaterial und ICD-O-3-Topographie zum Gewebetyp" type:"existingfile"`
+	Profiles    string `help:"Datei mit eigenen Profilen anstelle der enthaltenen Profile" type:"existingfile"`
+	Fixture     string `help:"Testdaten aus dieser Datei anstelle der Datenbank verwenden" type:"existingfile"`
+	FromExtract string `help:"Daten aus diesem Extrakt (Datei oder Verzeichnis) anstelle der Datenbank verwenden" type:"path"`
+	History     string `help:"Datei mit der Historie erstellter Exporte" default:"${history}" type:"path"`
 }
 
 type CLI struct {
 	Globals
+
+	Export    ExportCmd    `cmd:"" default:"withargs" help:"Export GRZ metadata template"`
+	Web       WebCmd       `cmd:"" help:"Start web frontend for metadata export"`
+	Diff      DiffCmd      `cmd:"" help:"Compare Onkostar data with previously exported metadata"`
+	Extract   ExtractCmd   `cmd:"" help:"Extract Onkostar data for use without database connection"`
+	Cases     CasesCmd     `cmd:"" help:"Find samples by patient, case or sample date and start export"`
+	Pending   PendingCmd   `cmd:"" help:"List cases with MV consent and sequencing but without export"`
+	Deadlines DeadlinesCmd `cmd:"" help:"Report elapsed days since sample and consent for cases without export"`
+	Stats     StatsCmd     `cmd:"" help:"Aggregate exports by quarter for reporting"`
+}
+
+type ExportCmd struct {
+	SubmissionDir         string `help:"Verzeichnis für eine Einreichung mit grz-cli"`
+	FilesDir              string `help:"Verzeichnis mit den referenzierten Sequenzierdaten" default:"." type:"existingdir"`
+	CopyFiles             bool   `help:"Sequenzierdaten kopieren anstatt sie zu verlinken"`
+	Merge                 bool   `help:"Vorhandene Ausgabedatei aktualisieren und manuelle Angaben beibehalten"`
+	Report                string `help:"Zusätzlich einen HTML-Bericht in diese Datei schreiben"`
+	Purity                string `help:"Tumorzellgehalt aus PURPLE- oder Sequenza-Ergebnis übernehmen" type:"existingfile"`
+	SampleSheet           string `help:"Barcodes aus Illumina SampleSheet.csv oder Ion Torrent Run-Plan (CSV) übernehmen" type:"existingfile"`
+	Coverage              string `help:"Anteil der Zielregionen über Mindestabdeckung aus mosdepth-Ergebnis (*.regions.bed.gz) berechnen" type:"existingfile"`
+	ReadLengths           bool   `help:"Leselängen aus FASTQ- und BAM-Dateien ermitteln"`
+	IgnoreReferenceGenome bool   `help:"Export trotz abweichendem Referenzgenom der Sequenzierdaten fortsetzen"`
+	SchemaVersion         string `help:"Ausgabe für diese Version des GRZ-Metadatenschemas"`
+	SchemaDir             string `help:"Verzeichnis mit GRZ-Metadatenschemas ('<Version>.json')" default:"${schemas}" type:"path"`
+	Format                string `help:"Ausgabeformat der Vorlage (json, yaml)" enum:"json,yaml" default:"json"`
+}
+
+func (cmd *ExportCmd) Run(globals *Globals, repository Repository) error {
+	form := NewForm(repository)
+	form.Init()
+	_ = form.Run()
+
+	request := MetadataRequest{
+		SampleId:   globals.SampleId,
+		Fallnummer: form.selectedFallnummer,
+		Ik:         form.selectedIk,
+		Profile:    form.selectedProfile,
+		Grz:        form.selectedGrz,
+		Kdk:        form.selectedKdk,
+	}
+
+	data, err := createMetadata(repository, request)
+	if err != nil {
+		return fmt.Errorf("cannot fetch metadata: %w", err)
+	}
+
+	if len(cmd.Purity) > 0 {
+		tumorCellCount, err := readPurity(cmd.Purity)
+		if err != nil {
+			return err
+		}
+		addTumorCellCount(data, *tumorCellCount)
+	}
+
+	if cmd.Merge {
+		if data, err = cmd.merge(globals, data); err != nil {
+			return err
+		}
+	}
+
+	// Unknown Kostenträger codes are not mapped and the coverage type is required by the GRZ
+	if len(data.Submission.CoverageType) == 0 {
+		return fmt.Errorf("no coverage type for sample '%s', add the Kostenträger code to the mappings", request.SampleId)
+	}
+
+	// Applied after merge to include flowcell and lane of files added to an existing file
+	if len(cmd.SampleSheet) > 0 {
+		sampleSheet, err := readSampleSheet(cmd.SampleSheet)
+		if err != nil {
+			return err
+		}
+		if err := applySampleSheet(data, sampleSheet, request.SampleId); err != nil {
+			return err
+		}
+	}
+
+	if profile := FindProfile(request.Ik, request.Profile); profile != nil {
+		if err := addTargetedRegions(data, profile, cmd.FilesDir, cmd.Coverage); err != nil {
+			return err
+		}
+	} else if len(cmd.Coverage) > 0 {
+		return fmt.Errorf("coverage requires a profile with targeted regions")
+	}
+
+	applyVcfHeaders(data, cmd.FilesDir)
+	if cmd.ReadLengths {
+		applyReadLengths(data, cmd.FilesDir)
+	}
+	mismatches, warnings := checkReferenceGenome(data, cmd.FilesDir)
+	for _, message := range append(mismatches, warnings...) {
+		fmt.Fprintf(os.Stderr, "\033[33m⚠ Referenzgenom - %s\033[0m\n", message)
+	}
+	if len(mismatches) > 0 && !cmd.IgnoreReferenceGenome {
+		return fmt.Errorf("reference genome does not match sequencing data, use --ignore-reference-genome to export anyway")
+	}
+	for _, message := range checkRnaLabData(data) {
+		fmt.Fprintf(os.Stderr, "\033[33m⚠ RNA - %s\033[0m\n", message)
+	}
+	for _, message := range checkLongReadLabData(data) {
+		fmt.Fprintf(os.Stderr, "\033[33m⚠ Long-Read - %s\033[0m\n", message)
+	}
+
+	j, err := cmd.marshal(data)
+	if err != nil {
+		return err
+	}
+	// The report shows the metadata as written, i.e. converted to the selected schema version
+	reported := data
+	if len(cmd.SchemaVersion) > 0 {
+		converted, err := metadata.UnmarshalMetadata(j)
+		if err != nil {
+			return fmt.Errorf("cannot read converted metadata: %w", err)
+		}
+		reported = &converted
+	}
+
+	if len(cmd.SubmissionDir) > 0 {
+		if err := writeSubmission(data, j, cmd.SubmissionDir, cmd.FilesDir, cmd.CopyFiles); err != nil {
+			return err
+		}
+		fmt.Printf("\033[32m✅ Einreichung wurde im Verzeichnis '%s' angelegt.\033[0m\n", cmd.SubmissionDir)
+		addHistory(globals, newHistoryEntry(HistorySubmission, request, data, cmd.SubmissionDir))
+		return cmd.report(request, reported)
+	}
+
+	// grz-cli requires JSON, YAML is only used for templates
+	if cmd.Format == "yaml" {
+		if j, err = jsonToYaml(j); err != nil {
+			return err
+		}
+	}
+
+	if len(globals.Filename) == 0 {
+		fmt.Println(string(j))
+		return cmd.report(request, reported)
+	}
+	if err := os.WriteFile(globals.Filename, j, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("\033[32m✅ Ermittelte Daten wurden als Vorlage in die Datei '%s' geschrieben.\033[0m\n", globals.Filename)
+	addHistory(globals, newHistoryEntry(HistoryTemplate, request, data, globals.Filename))
+	return cmd.report(request, reported)
+}
+
+// report writes the HTML report, if requested. It is written only after the output has been written successfully.
+func (cmd *ExportCmd) report(request MetadataRequest, data *metadata.Metadata) error {
+	if len(cmd.Report) == 0 {
+		return nil
+	}
+	if err := writeReport(cmd.Report, request, data, cmd.SchemaVersion); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "\033[32m✅ Bericht wurde in die Datei '%s' geschrieben.\033[0m\n", cmd.Report)
+	return nil
+}
+
+// marshal returns the metadata as JSON, converted to the selected schema version if any
+func (cmd *ExportCmd) marshal(data *metadata.Metadata) ([]byte, error) {
+	if len(cmd.SchemaVersion) == 0 {
+		return json.MarshalIndent(data, "", "  ")
+	}
+	converter, err := NewSchemaConverter(cmd.SchemaDir, cmd.SchemaVersion)
+	if err != nil {
+		return nil, err
+	}
+	converted, warnings := converter.Convert(data)
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "\033[33m⚠ Schema - %s\033[0m\n", warning)
+	}
+	return json.MarshalIndent(converted, "", "  ")
+}
+
+func addHistory(globals *Globals, entry HistoryEntry) {
+	if err := appendHistory(globals.History, entry); err != nil {
+		log.Printf("Cannot write export history: %s\n", err.Error())
+	}
+}
+
+// merge applies current data to an existing output file, if any, and reports conflicting values
+func (cmd *ExportCmd) merge(globals *Globals, data *metadata.Metadata) (*metadata.Metadata, error) {
+	filename := globals.Filename
+	if len(cmd.SubmissionDir) > 0 {
+		filename = filepath.Join(cmd.SubmissionDir, "metadata", "metadata.json")
+	}
+	if len(filename) == 0 {
+		return nil, fmt.Errorf("merge requires --filename or --submission-dir")
+	}
+	if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
+		return data, nil
+	}
+
+	existing, err := readMetadataFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	for _, conflict := range mergeMetadata(existing, data) {
+		fmt.Printf("\033[33m⚠ Geänderter Wert in Onkostar/Profil - %s\033[0m\n", conflict)
+	}
+	return existing, nil
 }
 
 func initCLI() {
@@ -67,6 +274,10 @@ func initCLI() {
 			Compact: true,
 		}),
 		kong.Configuration(kong.JSON, fmt.Sprintf("%s/.osdb-config.json", homedir)),
+		kong.Vars{
+			"history": fmt.Sprintf("%s/.os2grzmeta-history.jsonl", homedir),
+			"schemas": fmt.Sprintf("%s/.os2grzmeta-schemas", homedir),
+		},
 	)
 }
 
@@ -85,90 +296,223 @@ func initDb(dbCfg mysql.Config) (*sql.DB, error) {
 func main() {
 	initCLI()
 
-	if len(cli.Password) == 0 {
-		_ = huh.NewInput().Title("Passwort").
-			Value(&cli.Password).
-			EchoMode(huh.EchoModePassword).
-			WithTheme(huh.ThemeBase16()).
-			Run()
-	}
+	var db *sql.DB
+	defer func() {
+		if db == nil {
+			return
+		}
+		if err := db.Close(); err != nil {
+			log.Println("Cannot close database connection")
+		}
+	}()
 
-	dbCfg := mysql.Config{
-		User:                 cli.User,
-		Passwd:               cli.Password,
-		Net:                  "tcp",
-		Addr:                 fmt.Sprintf("%s:%d", cli.Host, cli.Port),
-		DBName:               cli.Database,
-		AllowNativePasswords: true,
-		TLSConfig:            cli.Ssl,
+	// Site-specific mappings are read once, invalid files must not result in exports using default mappings
+	mappings, err := ReadMappings(cli.Mappings)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tissueMapping, err := ReadTissueMapping(cli.Tissues)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := LoadProfiles(cli.Profiles); err != nil {
+		log.Fatal(err)
 	}
 
-	if dbx, dbErr := initDb(dbCfg); dbErr == nil {
-		db = dbx
-		defer func(db *sql.DB) {
-			err := db.Close()
+	// Repository will only be created for commands using it
+	repositoryProvider := func() (Repository, error) {
+		if len(cli.Fixture) > 0 {
+			return NewFixtureRepository(cli.Fixture)
+		}
+		if len(cli.FromExtract) > 0 {
+			source, err := NewExtractSource(cli.FromExtract)
 			if err != nil {
-				log.Println("Cannot close database connection")
+				return nil, err
 			}
-		}(db)
-	} else {
-		log.Fatalf("Cannot connect to Database: %s\n", dbErr.Error())
-	}
+			return NewQueryRepository(source, mappings, tissueMapping), nil
+		}
 
-	form := NewForm()
-	form.Init()
-	_ = form.Run()
+		if len(cli.Password) == 0 {
+			_ = huh.NewInput().Title("Passwort").
+				Value(&cli.Password).
+				EchoMode(huh.EchoModePassword).
+				WithTheme(huh.ThemeBase16()).
+				Run()
+		}
 
-	data, _ := fetchMetadata(form.selectedFallnummer)
-	if data == nil {
-		log.Fatalf("Cannot fetch metadata")
-	}
-
-	data.Submission.LocalCaseID = form.selectedFallnummer
-	data.Submission.ClinicalDataNodeID = form.selectedKdk
-	data.Submission.GenomicDataCenterID = form.selectedGrz
-
-	if profile := FindProfile(form.selectedIk, form.selectedProfile); profile != nil {
-		data.Submission.GenomicStudyType = metadata.GenomicStudyType(profile.GenomicStudyType)
-		data.Submission.GenomicStudySubtype = metadata.GenomicStudySubtype(profile.GenomicStudySubtype)
-		data.Submission.LabName = profile.LabName
-		data.Donors[0].LabData[0].LabDataName = profile.LabDataName
-		data.Donors[0].LabData[0].TissueTypeName = profile.TissueTypeName
-		data.Donors[0].LabData[0].SequenceType = metadata.SequenceType(profile.SequenceType)
-		data.Donors[0].LabData[0].SequenceSubtype = metadata.SequenceSubtype(profile.SequenceSubType)
-		data.Donors[0].LabData[0].FragmentationMethod = metadata.FragmentationMethod(profile.FragmentationMethod)
-		data.Donors[0].LabData[0].LibraryType = metadata.LibraryType(profile.LibraryType)
-		data.Donors[0].LabData[0].LibraryPrepKit = profile.LibraryPrepKit
-		data.Donors[0].LabData[0].LibraryPrepKitManufacturer = profile.LibraryPrepKitManufacturer
-		data.Donors[0].LabData[0].SequencerModel = profile.SequencerModel
-		data.Donors[0].LabData[0].SequencerManufacturer = profile.SequencerManufacturer
-		data.Donors[0].LabData[0].KitName = profile.KitName
-		data.Donors[0].LabData[0].KitManufacturer = profile.KitManufacturer
-		data.Donors[0].LabData[0].EnrichmentKitManufacturer = metadata.EnrichmentKitManufacturer(profile.EnrichmentKitManufacturer)
-		data.Donors[0].LabData[0].EnrichmentKitDescription = profile.EnrichmentKitDescription
-		data.Donors[0].LabData[0].SequencingLayout = metadata.SequencingLayout(profile.SequencingLayout)
-		data.Donors[0].LabData[0].TumorCellCount[0].Method = metadata.Method(profile.TumorCellCountMethod)
-		data.Donors[0].LabData[0].SequenceData.BioinformaticsPipelineName = profile.BioinformaticsPipelineName
-		data.Donors[0].LabData[0].SequenceData.BioinformaticsPipelineVersion = profile.BioinformaticsPipelineVersion
-		data.Donors[0].LabData[0].SequenceData.CallerUsed = append(data.Donors[0].LabData[0].SequenceData.CallerUsed, metadata.CallerUsed{
-			Name:    profile.CallerUsedName,
-			Version: profile.CallerUsedVersion,
-		})
-	}
-
-	j, _ := json.MarshalIndent(data, "", "  ")
-	if len(cli.Filename) == 0 {
-		fmt.Println(string(j))
-		return
+		dbCfg := mysql.Config{
+			User:                 cli.User,
+			Passwd:               cli.Password,
+			Net:                  "tcp",
+			Addr:                 fmt.Sprintf("%s:%d", cli.Host, cli.Port),
+			DBName:               cli.Database,
+			AllowNativePasswords: true,
+			TLSConfig:            cli.Ssl,
+		}
+
+		dbx, dbErr := initDb(dbCfg)
+		if dbErr != nil {
+			return nil, fmt.Errorf("cannot connect to Database: %w", dbErr)
+		}
+		db = dbx
+		return NewQueryRepository(NewMySqlSource(db, cli.QueryDir), mappings, tissueMapping), nil
 	}
-	if err := os.WriteFile(cli.Filename, j, 0644); err != nil {
+	context.Bind(RepositoryProvider(repositoryProvider))
+	if err := context.BindToProvider(repositoryProvider); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := context.Run(&cli.Globals); err != nil {
 		log.Fatal(err)
-	} else {
-		fmt.Printf("\033[32m✅ Ermittelte Daten wurden als Vorlage in die Datei '%s' geschrieben.\033[0m\n", cli.Filename)
 	}
 }
 
+// RepositoryProvider creates the repository on demand, for commands using Onkostar data only if requested
+type RepositoryProvider func() (Repository, error)
+
+// MetadataRequest contains all selections required to create a metadata template
+type MetadataRequest struct {
+	SampleId   string `json:"sampleId"`
+	Fallnummer string `json:"fallnummer"`
+	Ik         string `json:"ik"`
+	Profile    string `json:"profile"`
+	Grz        string `json:"grz"`
+	Kdk        string `json:"kdk"`
+}
+
+func createMetadata(repository Repository, request MetadataRequest) (*metadata.Metadata, error) {
+	data, err := repository.FetchMetadata(request.SampleId, request.Fallnummer)
+	if err != nil {
+		return nil, err
+	}
+	if len(data.Donors) == 0 || len(data.Donors[0].LabData) == 0 {
+		return nil, fmt.Errorf("no data found for sample id '%s'", request.SampleId)
+	}
+
+	data.Submission.LocalCaseID = request.Fallnummer
+	data.Submission.ClinicalDataNodeID = request.Kdk
+	data.Submission.GenomicDataCenterID = request.Grz
+
+	if profile := FindProfile(request.Ik, request.Profile); profile != nil {
+		applyProfile(data, profile)
+	}
+
+	return data, nil
+}
+
+func applyProfile(data *metadata.Metadata, profile *Profile) {
+	data.Submission.GenomicStudyType = metadata.GenomicStudyType(profile.GenomicStudyType)
+	data.Submission.GenomicStudySubtype = metadata.GenomicStudySubtype(profile.GenomicStudySubtype)
+	data.Submission.LabName = profile.LabName
+
+	// Each profile is applied to the first lab datum of its sequence type, e.g. DNA and RNA of the same sample
+	applied := map[*Profile]bool{}
+	for idx := range data.Donors[0].LabData {
+		labProfile := profile.ForSequenceType(data.Donors[0].LabData[idx].SequenceType)
+		if labProfile == nil {
+			log.Printf("Warning: Profile '%s' contains no settings for sequence type '%s' of lab data %d\n", profile.Name, data.Donors[0].LabData[idx].SequenceType, idx)
+			continue
+		}
+		if applied[labProfile] {
+			continue
+		}
+		applied[labProfile] = true
+		applyLabDataProfile(&data.Donors[0].LabData[idx], labProfile)
+	}
+}
+
+func applyLabDataProfile(labData *metadata.LabDatum, profile *Profile) {
+	labData.LabDataName = profile.LabDataName
+	// Keep tissue type derived from Onkostar data consistent with its ID
+	if len(labData.TissueTypeID) == 0 {
+		labData.TissueTypeName = profile.TissueTypeName
+	}
+	labData.SequenceType = metadata.SequenceType(strings.ToLower(profile.SequenceType))
+	labData.SequenceSubtype = metadata.SequenceSubtype(profile.SequenceSubType)
+	labData.FragmentationMethod = metadata.FragmentationMethod(profile.FragmentationMethod)
+	labData.LibraryType = metadata.LibraryType(profile.LibraryType)
+	labData.LibraryPrepKit = profile.LibraryPrepKit
+	labData.LibraryPrepKitManufacturer = profile.LibraryPrepKitManufacturer
+	labData.SequencerModel = profile.SequencerModel
+	labData.SequencerManufacturer = profile.SequencerManufacturer
+	labData.KitName = profile.KitName
+	labData.KitManufacturer = profile.KitManufacturer
+	labData.EnrichmentKitManufacturer = metadata.EnrichmentKitManufacturer(profile.EnrichmentKitManufacturer)
+	labData.EnrichmentKitDescription = profile.EnrichmentKitDescription
+	labData.SequencingLayout = metadata.SequencingLayout(profile.SequencingLayout)
+	if len(profile.TumorCellCountMethod) > 0 {
+		for idx := range labData.TumorCellCount {
+			labData.TumorCellCount[idx].Method = metadata.Method(profile.TumorCellCountMethod)
+		}
+	}
+	if labData.SequenceData == nil {
+		labData.SequenceData = &metadata.SequenceData{Files: []metadata.File{}}
+	}
+	labData.SequenceData.BioinformaticsPipelineName = profile.BioinformaticsPipelineName
+	labData.SequenceData.BioinformaticsPipelineVersion = profile.BioinformaticsPipelineVersion
+	labData.SequenceData.NonCodingVariants = profile.NonCodingVariants
+	if profile.MinCoverage > 0 {
+		labData.SequenceData.MinCoverage = profile.MinCoverage
+	}
+	labData.SequenceData.CallerUsed = append(labData.SequenceData.CallerUsed, metadata.CallerUsed{
+		Name:    profile.CallerUsedName,
+		Version: profile.CallerUsedVersion,
+	})
+}
+
+// Option is a selectable value with a label, used by terminal form and web frontend
+type Option struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+var genomicDataCenters = []Option{
+	{"GRZK00001 (GRZ Köln)", "GRZK00001"},
+	{"GRZTUE002 (GRZ Tübingen)", "GRZTUE002"},
+	{"GRZHD0003 (GRZ Heidelberg)", "GRZHD0003"},
+	{"GRZDD0004 (GRZ Dresden)", "GRZDD0004"},
+	{"GRZM00006 (GRZ München)", "GRZM00006"},
+	{"GRZB00007 (GRZ Berlin)", "GRZB00007"},
+}
+
+var clinicalDataNodes = []Option{
+	{"KDKDD0001 - GfH-NET (Universitätsklinikum Dresden)", "KDKDD0001"},
+	{"KDKTUE002 - NSE (Universitätsklinikum Tübingen)", "KDKTUE002"},
+	{"KDKL00003 - DK-FBREK (Universität Leipzig)", "KDKL00003"},
+	{"KDKL00004 - DK-FDK (Universität Leipzig)", "KDKL00004"},
+	{"KDKTUE005 - DNPM (Universitätsklinikum Tübingen)", "KDKTUE005"},
+	{"KDKHD0006 - NCT/DKTK MASTER (NCT Heidelberg)", "KDKHD0006"},
+	{"KDKK00007 - nNGM (Universitätsklinikum Köln)", "KDKK00007"},
+}
+
+func huhOptions(options []Option) []huh.Option[string] {
+	var result []huh.Option[string]
+	for _, option := range options {
+		result = append(result, huh.NewOption(option.Label, option.Value))
+	}
+	return result
+}
+
+func optionLabel(options []Option, value string) string {
+	for _, option := range options {
+		if option.Value == value {
+			return option.Label
+		}
+	}
+	return value
+}
+
+func hasOption(options []Option, value string) bool {
+	for _, option := range options {
+		if option.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
 type Form struct {
+	repository           Repository
 	innerForm            *huh.Form
 	availableFallnummern []string
 	selectedIk           string
@@ -178,8 +522,9 @@ type Form struct {
 	selectedFallnummer   string
 }
 
-func NewForm() *Form {
+func NewForm(repository Repository) *Form {
 	return &Form{
+		repository:           repository,
 		availableFallnummern: make([]string, 0),
 	}
 }
@@ -205,7 +550,7 @@ func (f *Form) Init() {
 					fallnummerOptions := []huh.Option[string]{
 						huh.NewOption("--- (Keine Angabe)", ""),
 					}
-					if fallnummern, err := fetchFallnummern(); err == nil {
+					if fallnummern, err := f.repository.FetchFallnummern(cli.SampleId); err == nil {
 						for _, option := range fallnummern {
 							fallnummerOptions = append(fallnummerOptions, huh.NewOption(option, option))
 						}
@@ -243,265 +588,15 @@ func (f *Form) Init() {
 				Description("Profil for LabData - Siehe auch Formular 'Molekulargenetische Untersuchung'"),
 			huh.NewSelect[string]().
 				Title("Genomrechenzentrum").
-				Options(
-					huh.NewOption("GRZK00001 (GRZ Köln)", "GRZK00001"),
-					huh.NewOption("GRZTUE002 (GRZ Tübingen)", "GRZTUE002"),
-					huh.NewOption("GRZHD0003 (GRZ Heidelberg)", "GRZHD0003"),
-					huh.NewOption("GRZDD0004 (GRZ Dresden)", "GRZDD0004"),
-					huh.NewOption("GRZM00006 (GRZ München)", "GRZM00006"),
-					huh.NewOption("GRZB00007 (GRZ Berlin)", "GRZB00007"),
-				).
+				Options(huhOptions(genomicDataCenters)...).
 				Value(&f.selectedGrz).
 				Description("Zu verwendendes Genomrechenzentrum"),
 			huh.NewSelect[string]().
 				Title("Klinischer Datenknoten").
-				Options(
-					huh.NewOption("KDKDD0001 - GfH-NET (Universitätsklinikum Dresden)", "KDKDD0001"),
-					huh.NewOption("KDKTUE002 - NSE (Universitätsklinikum Tübingen)", "KDKTUE002"),
-					huh.NewOption("KDKL00003 - DK-FBREK (Universität Leipzig)", "KDKL00003"),
-					huh.NewOption("KDKL00004 - DK-FDK (Universität Leipzig)", "KDKL00004"),
-					huh.NewOption("KDKTUE005 - DNPM (Universitätsklinikum Tübingen)", "KDKTUE005"),
-					huh.NewOption("KDKHD0006 - NCT/DKTK MASTER (NCT Heidelberg)", "KDKHD0006"),
-					huh.NewOption("KDKK00007 - nNGM (Universitätsklinikum Köln)", "KDKK00007"),
-				).
+				Options(huhOptions(clinicalDataNodes)...).
 				Value(&f.selectedKdk).
 				Description("Zu verwendender klinischer Datenknoten"),
 		).Title("Weitere Angaben zum Fall, Genomrechenzentrum und zum klinischen Datenknoten"),
 	).
 		WithTheme(huh.ThemeBase16())
 }
-
-func fetchMetadata(fallnummer string) (*metadata.Metadata, error) {
-	query := `SELECT
-				organisationunit.identifier AS submission_labname,
-				CASE
-					WHEN kostentraegertyp = 'GKV' THEN 'GKV'
-					WHEN kostentraegertyp = 'PKV' THEN 'PKV'
-					ELSE 'UNK'
-					END AS submission_coveragetype,
-    			patient.patienten_id as donors_items_pseudonym,
-				CASE
-					WHEN patient.geschlecht = 'm' THEN 'male'
-					WHEN patient.geschlecht = 'w' THEN 'female'
-					WHEN patient.geschlecht = 'u' THEN 'unknown'
-					ELSE 'other'
-					END AS donors_items_gender,
-				CONCAT(prop_probenmaterial.shortdesc, ' ', prop_nukleinsaeure.shortdesc) AS donors_items_labdata_items_labdataname,
-				dk_molekulargenetik.entnahmedatum AS donors_items_labdata_items_sampledate,
-				CASE
-				    WHEN dk_molekulargenetik.materialfixierung = 2 THEN 'cryo-frozen'
-				    WHEN dk_molekulargenetik.materialfixierung = 3 THEN 'ffpe'
-				    WHEN dk_molekulargenetik.materialfixierung = 9 THEN 'unknown'
-				    ELSE 'other'
-				    END AS donors_items_labdata_items_sampleconservation,
-				LOWER(prop_nukleinsaeure.shortdesc) AS donors_items_labdata_items_sequencetype,
-				CASE
-					WHEN dk_molekulargenetik.artdersequenzierung = 'WES' THEN 'wes'
-					WHEN dk_molekulargenetik.artdersequenzierung = 'WGS' THEN 'wgs'
-					WHEN dk_molekulargenetik.artdersequenzierung = 'PanelKit' THEN 'panel'
-					WHEN dk_molekulargenetik.artdersequenzierung = 'X' THEN 'unknown'
-					ELSE 'other'
-					END AS donors_items_labdata_items_librarytype,
-				dk_molekulargenetik.tumorzellgehalt AS donors_items_labdata_items_tumorCellCount_items_count,
-				CASE
-					WHEN dk_molekulargenetik.referenzgenom = 'HG19' THEN 'GRCh37'
-					WHEN dk_molekulargenetik.referenzgenom = 'HG38' THEN 'GRCh38'
-					END AS donors_items_labdata_items_sequencedata_referencegenome,
-				dk_molekulargenetik.panel AS x_panel # Use this to select default Kit info
-			FROM dk_molekulargenetik
-			JOIN prozedur ON (prozedur.id = dk_molekulargenetik.id)
-			JOIN patient ON (patient.id = prozedur.patient_id)
-			LEFT JOIN organisationunit ON (organisationunit.id = dk_molekulargenetik.durchfuehrendeoe_fachabteilung)
-			LEFT JOIN property_catalogue_version_entry AS prop_nukleinsaeure ON (
-				prop_nukleinsaeure.property_version_id = dk_molekulargenetik.nukleinsaeure_propcat_version
-					AND prop_nukleinsaeure.code = dk_molekulargenetik.nukleinsaeure)
-			LEFT JOIN property_catalogue_version_entry AS prop_probenmaterial ON (
-				prop_probenmaterial.property_version_id = dk_molekulargenetik.probenmaterial_propcat_version
-					AND prop_probenmaterial.code = dk_molekulargenetik.probenmaterial)
-			
-			# Hier die Einsendenummer aus Rohdaten-Datei in diesem Format einfügen
-			WHERE dk_molekulargenetik.entnahmedatum IS NOT NULL AND einsendenummer = ?`
-
-	var result = metadata.Metadata{}
-
-	if rows, err := db.Query(query, cli.SampleId); err == nil {
-		var submissionLabname sql.NullString
-		var submissionCoveragetype sql.NullString
-		var donorsPseudonym sql.NullString
-		var donorsGender sql.NullString
-		var donorsLabdataLabdataname sql.NullString
-		var donorsLabdataSampledate sql.NullString
-		var donorsLabdataSampleconservation sql.NullString
-		var donorsLabdataSequencetype sql.NullString
-		var donorsLabdataLibrarytype sql.NullString
-		var donorsLabdataTumorcellcount sql.NullString
-		var donorsLabdataSequencedataReferencegenome sql.NullString
-		var xPanel sql.NullString
-		for rows.Next() {
-			if err := rows.Scan(
-				&submissionLabname,
-				&submissionCoveragetype,
-				&donorsPseudonym,
-				&donorsGender,
-				&donorsLabdataLabdataname,
-				&donorsLabdataSampledate,
-				&donorsLabdataSampleconservation,
-				&donorsLabdataSequencetype,
-				&donorsLabdataLibrarytype,
-				&donorsLabdataTumorcellcount,
-				&donorsLabdataSequencedataReferencegenome,
-				&xPanel,
-			); err == nil {
-				if len(result.Donors) == 0 {
-					result.Submission = metadata.Submission{
-						SubmissionType: metadata.Initial,
-						CoverageType:   metadata.CoverageType(submissionCoveragetype.String),
-						DiseaseType:    metadata.Oncological,
-					}
-
-					result.Donors = []metadata.Donor{
-						{
-							DonorPseudonym: donorsPseudonym.String,
-							Gender:         metadata.Gender(donorsGender.String),
-							LabData:        []metadata.LabDatum{},
-							// Onkostar only holds index patient data
-							Relation: metadata.Index,
-						},
-					}
-
-					if consentMv, err := fetchMvConsent(fallnummer); err == nil && consentMv != nil {
-						result.Donors[0].MvConsent = *consentMv
-					}
-				}
-
-				tumorCellCount, _ := strconv.ParseFloat(donorsLabdataTumorcellcount.String, 64)
-
-				labData := metadata.LabDatum{
-					Barcode:            "NA",
-					LabDataName:        donorsLabdataLabdataname.String,
-					SampleDate:         donorsLabdataSampledate.String,
-					SampleConservation: metadata.SampleConservation(donorsLabdataSampleconservation.String),
-					SequenceType:       metadata.SequenceType(donorsLabdataSequencetype.String),
-					LibraryType:        metadata.LibraryType(donorsLabdataLibrarytype.String),
-					TumorCellCount: []metadata.TumorCellCount{
-						{
-							Count: tumorCellCount,
-							// Fixed value for UKW-CCC! Will be overwritten by profile
-							Method: metadata.Pathology,
-						},
-					},
-					SequenceData: &metadata.SequenceData{
-						// As mapped from Onkostar HG19 => GRCh37!
-						ReferenceGenome: metadata.ReferenceGenome(donorsLabdataSequencedataReferencegenome.String),
-						Files:           []metadata.File{},
-					},
-				}
-
-				result.Donors[0].LabData = append(result.Donors[0].LabData, labData)
-			} else {
-				return nil, err
-			}
-		}
-	} else {
-		return nil, err
-	}
-
-	return &result, nil
-}
-
-func fetchFallnummern() ([]string, error) {
-	query := `SELECT DISTINCT
-			dk_dnpm_kpa.fallnummermv
-		FROM dk_dnpm_kpa
-		JOIN dk_dnpm_therapieplan ON (dk_dnpm_therapieplan.ref_dnpm_klinikanamnese = dk_dnpm_kpa.id AND dk_dnpm_kpa.fallnummermv = ?)
-		OR dk_dnpm_therapieplan.id IN (
-			SELECT hauptprozedur_id FROM dk_molekulargenetik
-			JOIN dk_dnpm_uf_rebiopsie ON (dk_dnpm_uf_rebiopsie.ref_molekulargenetik = dk_molekulargenetik.id)
-			JOIN prozedur ON (prozedur.id = dk_dnpm_uf_rebiopsie.id)
-			WHERE einsendenummer = ?
-		)
-		OR dk_dnpm_therapieplan.id IN (
-			SELECT hauptprozedur_id FROM dk_molekulargenetik
-			JOIN dk_dnpm_uf_reevaluation ON (dk_dnpm_uf_reevaluation.ref_molekulargenetik = dk_molekulargenetik.id)
-			JOIN prozedur ON (prozedur.id = dk_dnpm_uf_reevaluation.id)
-			WHERE einsendenummer = ?
-		)
-		OR dk_dnpm_therapieplan.id IN (
-			SELECT hauptprozedur_id FROM dk_molekulargenetik
-			JOIN dk_dnpm_uf_einzelempfehlung ON (dk_dnpm_uf_einzelempfehlung.ref_molekulargenetik = dk_molekulargenetik.id)
-			JOIN prozedur ON (prozedur.id = dk_dnpm_uf_einzelempfehlung.id)
-			WHERE einsendenummer = ?
-		)`
-
-	var result []string
-
-	if rows, err := db.Query(query, cli.SampleId, cli.SampleId, cli.SampleId, cli.SampleId); err == nil {
-		var caseId sql.NullString
-		for rows.Next() {
-			if err := rows.Scan(&caseId); err == nil {
-				result = append(result, caseId.String)
-			}
-		}
-	} else {
-		return nil, err
-	}
-
-	return result, nil
-}
-
-func fetchMvConsent(caseId string) (*metadata.MvConsent, error) {
-	query := `SELECT
-			date,
-			version,
-			sequencing,
-			caseidentification,
-			reidentification
-		FROM dk_dnpm_uf_consentmvverlauf
-		JOIN prozedur ON (prozedur.id = dk_dnpm_uf_consentmvverlauf.id)
-		WHERE prozedur.hauptprozedur_id IN (
-			SELECT dk_dnpm_consentmv.id
-			FROM dk_dnpm_kpa
-			JOIN dk_dnpm_consentmv ON (dk_dnpm_consentmv.id = dk_dnpm_kpa.consentmv64e)
-			WHERE fallnummermv = ?
-		)
-		ORDER BY dk_dnpm_uf_consentmvverlauf.date DESC
-		LIMIT 1;`
-
-	if rows, err := db.Query(query, caseId); err == nil {
-		var date sql.NullString
-		var version sql.NullString
-		var sequencing sql.NullString
-		var caseidentification sql.NullString
-		var reidentification sql.NullString
-
-		for rows.Next() {
-			if err := rows.Scan(&date, &version, &sequencing, &caseidentification, &reidentification); err == nil {
-				mvConsent := metadata.MvConsent{
-					PresentationDate: &date.String,
-					Version:          version.String,
-					Scope: []metadata.Scope{
-						{
-							Type:   metadata.Type(sequencing.String),
-							Date:   date.String,
-							Domain: metadata.MvSequencing,
-						},
-						{
-							Type:   metadata.Type(reidentification.String),
-							Date:   date.String,
-							Domain: metadata.ReIdentification,
-						},
-						{
-							Type:   metadata.Type(caseidentification.String),
-							Date:   date.String,
-							Domain: metadata.CaseIdentification,
-						},
-					},
-				}
-
-				return &mvConsent, nil
-			}
-		}
-	}
-
-	return nil, nil
-}
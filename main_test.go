@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata/golden")
+
+// assertGolden compares the JSON representation of value with the golden file 'testdata/golden/<name>.json'
+func assertGolden(t *testing.T, name string, value any) {
+	t.Helper()
+	actual, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual = append(actual, '\n')
+
+	filename := filepath.Join("testdata", "golden", name+".json")
+	if *update {
+		if err := os.WriteFile(filename, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("cannot read golden file (use -update to create it): %s", err.Error())
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("result differs from golden file '%s':\n%s", filename, actual)
+	}
+}
+
+func fixtureRepository(t *testing.T) *FixtureRepository {
+	t.Helper()
+	repository, err := NewFixtureRepository(filepath.Join("testdata", "fixture.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repository
+}
+
+func TestCreateMetadata(t *testing.T) {
+	tests := []struct {
+		golden  string
+		profile string
+	}{
+		{"metadata-without-profile", ""},
+		{"metadata-ocaplus", "UKW - OCAplus (CCC-Patho)"},
+		{"metadata-exom", "UKW - Exom (CCC-Patho)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.golden, func(t *testing.T) {
+			data, err := createMetadata(fixtureRepository(t), MetadataRequest{
+				SampleId:   "H/2025/0001",
+				Fallnummer: "FALL-2025-0001",
+				Ik:         "260960079",
+				Profile:    test.profile,
+				Grz:        "GRZK00001",
+				Kdk:        "KDKK00007",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, test.golden, data)
+		})
+	}
+}
+
+func TestCreateMetadataWithoutData(t *testing.T) {
+	if _, err := createMetadata(fixtureRepository(t), MetadataRequest{SampleId: "H/2025/9999"}); err == nil {
+		t.Error("expected error for unknown sample id")
+	}
+}
+
+func TestApplyProfileUsesProfilePerSequenceType(t *testing.T) {
+	profile := FindProfile("260960079", "UKW - OCAplus (CCC-Patho)")
+	if profile == nil || profile.Rna == nil {
+		t.Fatal("expected profile with RNA settings")
+	}
+
+	data := &metadata.Metadata{
+		Donors: []metadata.Donor{
+			{
+				LabData: []metadata.LabDatum{
+					{SequenceType: metadata.Rna},
+					{SequenceType: metadata.Dna},
+					{SequenceType: metadata.Dna},
+				},
+			},
+		},
+	}
+	applyProfile(data, profile)
+
+	labData := data.Donors[0].LabData
+	if labData[0].LabDataName != profile.Rna.LabDataName || labData[0].SequenceType != metadata.Rna {
+		t.Errorf("expected RNA profile for RNA lab data, got '%s'", labData[0].LabDataName)
+	}
+	if labData[1].LabDataName != profile.LabDataName || labData[1].SequenceType != metadata.Dna {
+		t.Errorf("expected DNA profile for DNA lab data, got '%s'", labData[1].LabDataName)
+	}
+	// Profiles are applied to the first lab datum of the sequence type only
+	if len(labData[2].LabDataName) > 0 {
+		t.Errorf("expected no profile for second DNA lab data, got '%s'", labData[2].LabDataName)
+	}
+}
+
+func TestApplyProfileWithoutRnaSettings(t *testing.T) {
+	profile := FindProfile("260960079", "UKW - Exom (CCC-Patho)")
+	if profile == nil {
+		t.Fatal("expected profile")
+	}
+
+	data := &metadata.Metadata{
+		Donors: []metadata.Donor{
+			{
+				LabData: []metadata.LabDatum{
+					{SequenceType: metadata.Rna, LabDataName: "Tumorgewebe RNA"},
+				},
+			},
+		},
+	}
+	applyProfile(data, profile)
+
+	if labData := data.Donors[0].LabData[0]; labData.LabDataName != "Tumorgewebe RNA" || labData.SequenceData != nil {
+		t.Errorf("expected RNA lab data to be unchanged, got '%s'", labData.LabDataName)
+	}
+	if data.Submission.GenomicStudyType != metadata.GenomicStudyType(profile.GenomicStudyType) {
+		t.Errorf("expected submission settings of profile, got '%s'", data.Submission.GenomicStudyType)
+	}
+}
+
+func TestApplyLabDataProfileKeepsTissueType(t *testing.T) {
+	profile := &Profile{TissueTypeName: "Tumorgewebe", TumorCellCountMethod: "bioinformatics", MinCoverage: 30}
+
+	labData := metadata.LabDatum{
+		TissueTypeID:   "UBERON:0002048",
+		TissueTypeName: "lung",
+		TumorCellCount: []metadata.TumorCellCount{{Count: 40, Method: metadata.Pathology}},
+	}
+	applyLabDataProfile(&labData, profile)
+
+	if labData.TissueTypeName != "lung" {
+		t.Errorf("expected tissue type derived from Onkostar, got '%s'", labData.TissueTypeName)
+	}
+	if labData.TumorCellCount[0].Method != metadata.Bioinformatics {
+		t.Errorf("expected tumor cell count method of profile, got '%s'", labData.TumorCellCount[0].Method)
+	}
+	if labData.SequenceData == nil || labData.SequenceData.MinCoverage != 30 {
+		t.Error("expected sequence data with minimum coverage of profile")
+	}
+}
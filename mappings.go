@@ -0,0 +1,59 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ValueMapping maps Onkostar codes of a result column to metadata values
+type ValueMapping struct {
+	Values map[string]string `json:"values"`
+	// Default is used for unmapped codes, which will be reported nevertheless
+	Default *string `json:"default,omitempty"`
+}
+
+//go:embed mappings.json
+var mappings []byte
+
+// ReadMappings returns the value mappings by column name.
+// Mappings in the given file, if any, replace default mappings of the same column.
+func ReadMappings(filename string) (map[string]ValueMapping, error) {
+	result := map[string]ValueMapping{}
+	if err := json.Unmarshal(mappings, &result); err != nil {
+		return nil, err
+	}
+	if len(filename) == 0 {
+		return result, nil
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read mappings: %w", err)
+	}
+	custom := map[string]ValueMapping{}
+	if err := json.Unmarshal(content, &custom); err != nil {
+		return nil, fmt.Errorf("cannot read mappings '%s': %w", filename, err)
+	}
+	for column, mapping := range custom {
+		result[column] = mapping
+	}
+	return result, nil
+}
+
+// mapValue returns the mapped value for the code of the given column and whether the code is mapped.
+// The code is returned unchanged if there is no mapping for the column.
+// Unmapped codes result in the default value, if any, or an empty string.
+func mapValue(mappings map[string]ValueMapping, column string, code string) (string, bool) {
+	mapping, ok := mappings[column]
+	if !ok {
+		return code, true
+	}
+	if value, ok := mapping.Values[code]; ok {
+		return value, true
+	}
+	if mapping.Default != nil {
+		return *mapping.Default, false
+	}
+	return "", false
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// Conflict describes a field sourced from Onkostar or a profile with a changed value
+type Conflict struct {
+	Path     string `json:"path"`
+	Existing any    `json:"existing"`
+	Current  any    `json:"current"`
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s: '%s' => '%s'", c.Path, conflictValue(c.Existing), conflictValue(c.Current))
+}
+
+func conflictValue(value any) string {
+	if reflect.ValueOf(value).Kind() == reflect.String {
+		return fmt.Sprint(value)
+	}
+	j, _ := json.Marshal(value)
+	return string(j)
+}
+
+// readMetadataFile reads metadata from a JSON or YAML file
+func readMetadataFile(filename string) (*metadata.Metadata, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if isYamlFile(filename) {
+		if content, err = yamlToJson(content); err != nil {
+			return nil, fmt.Errorf("cannot read metadata file '%s': %w", filename, err)
+		}
+	}
+	data, err := metadata.UnmarshalMetadata(content)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read metadata file '%s': %w", filename, err)
+	}
+	return &data, nil
+}
+
+// mergeMetadata updates all fields in existing metadata sourced from Onkostar or profiles using current values.
+// Fields not sourced from Onkostar or profiles, like files or QC metrics, are kept.
+// Empty current values never replace existing values. Each changed value is reported as conflict.
+func mergeMetadata(existing *metadata.Metadata, current *metadata.Metadata) []Conflict {
+	var conflicts []Conflict
+
+	mergeField(&conflicts, "submission.localCaseId", &existing.Submission.LocalCaseID, current.Submission.LocalCaseID)
+	mergeField(&conflicts, "submission.clinicalDataNodeId", &existing.Submission.ClinicalDataNodeID, current.Submission.ClinicalDataNodeID)
+	mergeField(&conflicts, "submission.genomicDataCenterId", &existing.Submission.GenomicDataCenterID, current.Submission.GenomicDataCenterID)
+	mergeField(&conflicts, "submission.coverageType", &existing.Submission.CoverageType, current.Submission.CoverageType)
+	mergeField(&conflicts, "submission.diseaseType", &existing.Submission.DiseaseType, current.Submission.DiseaseType)
+	mergeField(&conflicts, "submission.genomicStudyType", &existing.Submission.GenomicStudyType, current.Submission.GenomicStudyType)
+	mergeField(&conflicts, "submission.genomicStudySubtype", &existing.Submission.GenomicStudySubtype, current.Submission.GenomicStudySubtype)
+	mergeField(&conflicts, "submission.labName", &existing.Submission.LabName, current.Submission.LabName)
+
+	if len(current.Donors) == 0 {
+		return conflicts
+	}
+	if len(existing.Donors) == 0 {
+		existing.Donors = current.Donors
+		return conflicts
+	}
+
+	// Onkostar only holds index patient data
+	donor, currentDonor := &existing.Donors[0], &current.Donors[0]
+	mergeField(&conflicts, "donors[0].donorPseudonym", &donor.DonorPseudonym, currentDonor.DonorPseudonym)
+	mergeField(&conflicts, "donors[0].gender", &donor.Gender, currentDonor.Gender)
+	mergeValue(&conflicts, "donors[0].mvConsent", &donor.MvConsent, currentDonor.MvConsent)
+
+	pairs := pairLabData(donor.LabData, currentDonor.LabData)
+	for idx, currentLabData := range currentDonor.LabData {
+		if pairs[idx] < 0 {
+			donor.LabData = append(donor.LabData, currentLabData)
+			continue
+		}
+		mergeLabData(&conflicts, fmt.Sprintf("donors[0].labData[%d]", pairs[idx]), &donor.LabData[pairs[idx]], currentLabData)
+	}
+
+	return conflicts
+}
+
+// pairLabData returns the index of the existing lab datum for each current lab datum or -1 if there is none.
+// Lab data are paired in order by sequence type and library type or, if not available, by sequence type only,
+// since the order of lab data may change between Onkostar queries.
+func pairLabData(existing []metadata.LabDatum, current []metadata.LabDatum) []int {
+	result := make([]int, len(current))
+	used := make([]bool, len(existing))
+	for idx := range result {
+		result[idx] = -1
+	}
+	matchers := []func(a, b metadata.LabDatum) bool{
+		func(a, b metadata.LabDatum) bool {
+			return a.SequenceType == b.SequenceType && a.LibraryType == b.LibraryType
+		},
+		func(a, b metadata.LabDatum) bool { return a.SequenceType == b.SequenceType },
+	}
+	for _, matches := range matchers {
+		for idx, currentLabData := range current {
+			if result[idx] >= 0 {
+				continue
+			}
+			for existingIdx, labData := range existing {
+				if !used[existingIdx] && matches(labData, currentLabData) {
+					used[existingIdx] = true
+					result[idx] = existingIdx
+					break
+				}
+			}
+		}
+	}
+	return result
+}
+
+func mergeLabData(conflicts *[]Conflict, path string, labData *metadata.LabDatum, current metadata.LabDatum) {
+	mergeField(conflicts, path+".labDataName", &labData.LabDataName, current.LabDataName)
+	mergeField(conflicts, path+".sampleDate", &labData.SampleDate, current.SampleDate)
+	mergeField(conflicts, path+".sampleConservation", &labData.SampleConservation, current.SampleConservation)
+	mergeField(conflicts, path+".sequenceType", &labData.SequenceType, current.SequenceType)
+	mergeField(conflicts, path+".sequenceSubtype", &labData.SequenceSubtype, current.SequenceSubtype)
+	mergeField(conflicts, path+".libraryType", &labData.LibraryType, current.LibraryType)
+	mergeValue(conflicts, path+".tissueOntology", &labData.TissueOntology, current.TissueOntology)
+	mergeField(conflicts, path+".tissueTypeId", &labData.TissueTypeID, current.TissueTypeID)
+	mergeField(conflicts, path+".tissueTypeName", &labData.TissueTypeName, current.TissueTypeName)
+	mergeField(conflicts, path+".fragmentationMethod", &labData.FragmentationMethod, current.FragmentationMethod)
+	mergeField(conflicts, path+".libraryPrepKit", &labData.LibraryPrepKit, current.LibraryPrepKit)
+	mergeField(conflicts, path+".libraryPrepKitManufacturer", &labData.LibraryPrepKitManufacturer, current.LibraryPrepKitManufacturer)
+	mergeField(conflicts, path+".sequencerModel", &labData.SequencerModel, current.SequencerModel)
+	mergeField(conflicts, path+".sequencerManufacturer", &labData.SequencerManufacturer, current.SequencerManufacturer)
+	mergeField(conflicts, path+".kitName", &labData.KitName, current.KitName)
+	mergeField(conflicts, path+".kitManufacturer", &labData.KitManufacturer, current.KitManufacturer)
+	mergeField(conflicts, path+".enrichmentKitManufacturer", &labData.EnrichmentKitManufacturer, current.EnrichmentKitManufacturer)
+	mergeField(conflicts, path+".enrichmentKitDescription", &labData.EnrichmentKitDescription, current.EnrichmentKitDescription)
+	mergeField(conflicts, path+".sequencingLayout", &labData.SequencingLayout, current.SequencingLayout)
+	for _, tumorCellCount := range current.TumorCellCount {
+		mergeTumorCellCount(conflicts, path+".tumorCellCount", labData, tumorCellCount)
+	}
+
+	if current.SequenceData == nil {
+		return
+	}
+	if labData.SequenceData == nil {
+		labData.SequenceData = current.SequenceData
+		return
+	}
+	sequenceData, currentSequenceData := labData.SequenceData, current.SequenceData
+	mergeField(conflicts, path+".sequenceData.referenceGenome", &sequenceData.ReferenceGenome, currentSequenceData.ReferenceGenome)
+	mergeField(conflicts, path+".sequenceData.bioinformaticsPipelineName", &sequenceData.BioinformaticsPipelineName, currentSequenceData.BioinformaticsPipelineName)
+	mergeField(conflicts, path+".sequenceData.bioinformaticsPipelineVersion", &sequenceData.BioinformaticsPipelineVersion, currentSequenceData.BioinformaticsPipelineVersion)
+	mergeField(conflicts, path+".sequenceData.nonCodingVariants", &sequenceData.NonCodingVariants, currentSequenceData.NonCodingVariants)
+	mergeField(conflicts, path+".sequenceData.minCoverage", &sequenceData.MinCoverage, currentSequenceData.MinCoverage)
+	mergeValue(conflicts, path+".sequenceData.callerUsed", &sequenceData.CallerUsed, currentSequenceData.CallerUsed)
+}
+
+func mergeField[T comparable](conflicts *[]Conflict, path string, existing *T, current T) {
+	var zero T
+	if current == zero || *existing == current {
+		return
+	}
+	if *existing != zero {
+		*conflicts = append(*conflicts, Conflict{Path: path, Existing: *existing, Current: current})
+	}
+	*existing = current
+}
+
+func mergeValue[T any](conflicts *[]Conflict, path string, existing *T, current T) {
+	if reflect.ValueOf(current).IsZero() || reflect.DeepEqual(*existing, current) {
+		return
+	}
+	if !reflect.ValueOf(*existing).IsZero() {
+		*conflicts = append(*conflicts, Conflict{Path: path, Existing: *existing, Current: current})
+	}
+	*existing = current
+}
+
+// mergeTumorCellCount updates the tumor cell count of the same method, other existing counts are kept
+func mergeTumorCellCount(conflicts *[]Conflict, path string, labData *metadata.LabDatum, current metadata.TumorCellCount) {
+	for idx, tumorCellCount := range labData.TumorCellCount {
+		if tumorCellCount.Method == current.Method {
+			mergeField(conflicts, fmt.Sprintf("%s[%s]", path, current.Method), &labData.TumorCellCount[idx].Count, current.Count)
+			return
+		}
+	}
+	labData.TumorCellCount = append(labData.TumorCellCount, current)
+}
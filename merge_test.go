@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+func TestPairLabData(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []metadata.LabDatum
+		current  []metadata.LabDatum
+		expected []int
+	}{
+		{
+			name:     "same order",
+			existing: []metadata.LabDatum{{SequenceType: metadata.Dna}, {SequenceType: metadata.Rna}},
+			current:  []metadata.LabDatum{{SequenceType: metadata.Dna}, {SequenceType: metadata.Rna}},
+			expected: []int{0, 1},
+		},
+		{
+			name:     "reverse order",
+			existing: []metadata.LabDatum{{SequenceType: metadata.Dna}, {SequenceType: metadata.Rna}},
+			current:  []metadata.LabDatum{{SequenceType: metadata.Rna}, {SequenceType: metadata.Dna}},
+			expected: []int{1, 0},
+		},
+		{
+			name: "library type preferred",
+			existing: []metadata.LabDatum{
+				{SequenceType: metadata.Dna, LibraryType: metadata.Wes},
+				{SequenceType: metadata.Dna, LibraryType: metadata.Panel},
+			},
+			current:  []metadata.LabDatum{{SequenceType: metadata.Dna, LibraryType: metadata.Panel}},
+			expected: []int{1},
+		},
+		{
+			name:     "sequence type only",
+			existing: []metadata.LabDatum{{SequenceType: metadata.Dna, LibraryType: metadata.Wes}},
+			current:  []metadata.LabDatum{{SequenceType: metadata.Dna, LibraryType: metadata.Panel}},
+			expected: []int{0},
+		},
+		{
+			name:     "new lab datum",
+			existing: []metadata.LabDatum{{SequenceType: metadata.Dna}},
+			current:  []metadata.LabDatum{{SequenceType: metadata.Rna}, {SequenceType: metadata.Dna}},
+			expected: []int{-1, 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := pairLabData(test.existing, test.current)
+			for idx, expected := range test.expected {
+				if actual[idx] != expected {
+					t.Errorf("expected lab data %d paired with %d, got %d", idx, expected, actual[idx])
+				}
+			}
+		})
+	}
+}
+
+func TestMergeMetadataPairsLabDataBySequenceType(t *testing.T) {
+	existing := &metadata.Metadata{
+		Donors: []metadata.Donor{{
+			LabData: []metadata.LabDatum{
+				{SequenceType: metadata.Dna, SampleDate: "2025-01-01"},
+				{SequenceType: metadata.Rna, SampleDate: "2025-01-01"},
+			},
+		}},
+	}
+	current := &metadata.Metadata{
+		Donors: []metadata.Donor{{
+			LabData: []metadata.LabDatum{
+				{SequenceType: metadata.Rna, SampleDate: "2025-01-02"},
+				{SequenceType: metadata.Dna, SampleDate: "2025-01-01"},
+			},
+		}},
+	}
+
+	conflicts := mergeMetadata(existing, current)
+
+	if len(conflicts) != 1 || conflicts[0].Path != "donors[0].labData[1].sampleDate" {
+		t.Fatalf("expected single conflict for RNA sample date, got %v", conflicts)
+	}
+	if labData := existing.Donors[0].LabData; len(labData) != 2 || labData[0].SequenceType != metadata.Dna || labData[1].SampleDate != "2025-01-02" {
+		t.Errorf("unexpected merged lab data %v", labData)
+	}
+}
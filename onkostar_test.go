@@ -0,0 +1,229 @@
+package main
+
+import (
+	gosql "database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	sqle "github.com/dolthub/go-mysql-server"
+	"github.com/dolthub/go-mysql-server/memory"
+	"github.com/dolthub/go-mysql-server/server"
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/go-sql-driver/mysql"
+	"github.com/sirupsen/logrus"
+)
+
+// onkostarDb starts an in-process MySQL compatible server seeded with the Onkostar schema and data in 'testdata/onkostar'
+func onkostarDb(t *testing.T) *gosql.DB {
+	t.Helper()
+	logrus.SetLevel(logrus.ErrorLevel)
+
+	database := memory.NewDatabase("onkostar")
+	database.BaseDatabase.EnablePrimaryKeyIndexes()
+	provider := memory.NewDBProvider(database)
+	engine := sqle.NewDefault(provider)
+	s, err := server.NewServer(server.Config{Protocol: "tcp", Address: "127.0.0.1:0"}, engine, sql.NewContext, memory.NewSessionBuilder(provider), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = s.Start()
+	}()
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+
+	dbCfg := mysql.Config{
+		User:                 "root",
+		Net:                  "tcp",
+		Addr:                 s.Listener.Addr().String(),
+		DBName:               "onkostar",
+		AllowNativePasswords: true,
+	}
+	db, err := initDb(dbCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	for _, filename := range []string{"01-schema.sql", "02-data.sql"} {
+		content, err := os.ReadFile(filepath.Join("testdata", "onkostar", filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, statement := range sqlStatements(string(content)) {
+			if _, err := db.Exec(statement); err != nil {
+				t.Fatalf("cannot execute statement of '%s': %s\n%s", filename, err.Error(), statement)
+			}
+		}
+	}
+	return db
+}
+
+// sqlStatements splits SQL script content into statements, comment lines are removed
+func sqlStatements(content string) []string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+	var result []string
+	for _, statement := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if statement = strings.TrimSpace(statement); len(statement) > 0 {
+			result = append(result, statement)
+		}
+	}
+	return result
+}
+
+func onkostarRepository(t *testing.T, queryDir string) *QueryRepository {
+	t.Helper()
+	mappings, err := ReadMappings("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tissueMapping, err := ReadTissueMapping("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewQueryRepository(NewMySqlSource(onkostarDb(t), queryDir), mappings, tissueMapping)
+}
+
+func TestOnkostarFetchMetadata(t *testing.T) {
+	data, err := onkostarRepository(t, "").FetchMetadata("H/2025/0001", "FALL-2025-0001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "metadata-onkostar", data)
+}
+
+func TestOnkostarFetchMetadataIgnoresTopographyOfBloodSample(t *testing.T) {
+	db := onkostarDb(t)
+	if _, err := db.Exec("UPDATE dk_molekulargenetik SET probenmaterial = 'B' WHERE id = 11"); err != nil {
+		t.Fatal(err)
+	}
+	tissueMapping, err := ReadTissueMapping("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := NewQueryRepository(NewMySqlSource(db, ""), map[string]ValueMapping{}, tissueMapping).FetchMetadata("H/2025/0001", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	labData := data.Donors[0].LabData
+	if labData[0].TissueTypeName != "lung" || labData[1].TissueTypeName != "blood" {
+		t.Errorf("expected tissue types 'lung' and 'blood', got '%s' and '%s'", labData[0].TissueTypeName, labData[1].TissueTypeName)
+	}
+}
+
+func TestOnkostarFetchMetadataOfUnknownSample(t *testing.T) {
+	data, err := onkostarRepository(t, "").FetchMetadata("H/2025/9999", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Donors) > 0 {
+		t.Errorf("expected no donors, got %d", len(data.Donors))
+	}
+}
+
+func TestOnkostarFetchFallnummern(t *testing.T) {
+	fallnummern, err := onkostarRepository(t, "").FetchFallnummern("H/2025/0001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(fallnummern, []string{"FALL-2025-0001"}) {
+		t.Errorf("expected [FALL-2025-0001], got %v", fallnummern)
+	}
+}
+
+func TestOnkostarFetchMvConsent(t *testing.T) {
+	repository := onkostarRepository(t, "")
+
+	consent, err := repository.FetchMvConsent("FALL-2025-0001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Same consent as in fixture
+	assertGolden(t, "mvconsent-fixture", consent)
+
+	if consent, _ := repository.FetchMvConsent("FALL-2025-9999"); consent != nil {
+		t.Errorf("expected no consent for unknown case, got %v", consent)
+	}
+}
+
+func TestOnkostarFetchCases(t *testing.T) {
+	repository := onkostarRepository(t, "")
+
+	tests := []struct {
+		name     string
+		filter   CaseFilter
+		expected int
+	}{
+		{"patient", CaseFilter{PatientId: "P000001"}, 2},
+		{"case", CaseFilter{Fallnummer: "FALL-2025-0001"}, 1},
+		{"unknown case", CaseFilter{Fallnummer: "FALL-2025-9999"}, 0},
+		{"date range", CaseFilter{From: "2025-03-01", To: "2025-03-31"}, 2},
+		{"date after", CaseFilter{From: "2025-04-01"}, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cases, err := repository.FetchCases(test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cases) != test.expected {
+				t.Errorf("expected %d cases, got %d", test.expected, len(cases))
+			}
+			for _, c := range cases {
+				if c.SampleId != "H/2025/0001" || !slices.Equal(c.Fallnummern, []string{"FALL-2025-0001"}) {
+					t.Errorf("unexpected case %v", c)
+				}
+			}
+		})
+	}
+}
+
+func TestOnkostarFetchSequencedCases(t *testing.T) {
+	sequencedCases, err := onkostarRepository(t, "").FetchSequencedCases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []SequencedCase{
+		{Fallnummer: "FALL-2025-0001", SampleId: "H/2025/0001", SampleDate: "2025-03-12", ConsentDate: "2025-02-20", Sequencing: "permit"},
+	}
+	if !slices.Equal(sequencedCases, expected) {
+		t.Errorf("expected %v, got %v", expected, sequencedCases)
+	}
+}
+
+func TestOnkostarFetchTopography(t *testing.T) {
+	if topography := onkostarRepository(t, "").FetchTopography("H/2025/0001"); topography != "C34.1" {
+		t.Errorf("expected 'C34.1', got '%s'", topography)
+	}
+}
+
+func TestOnkostarUsesQueryDir(t *testing.T) {
+	queryDir := t.TempDir()
+	query := `SELECT fallnummermv AS fallnummer # Fallnummer? Siehe Formular 'DNPM Klinik/Anamnese'
+FROM dk_dnpm_kpa
+WHERE fallnummermv LIKE CONCAT(?, '%') AND fallnummermv <> '?'`
+	if err := os.WriteFile(filepath.Join(queryDir, "fallnummern.sql"), []byte(query), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fallnummern, err := onkostarRepository(t, queryDir).FetchFallnummern("FALL-2025")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(fallnummern, []string{"FALL-2025-0001"}) {
+		t.Errorf("expected [FALL-2025-0001], got %v", fallnummern)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// SequencedCase is a sequenced sample linked to a case and the state of the latest MV consent
+type SequencedCase struct {
+	Fallnummer  string        `json:"fallnummer"`
+	SampleId    string        `json:"sampleId"`
+	SampleDate  string        `json:"sampleDate"`
+	ConsentDate string        `json:"consentDate"`
+	Sequencing  metadata.Type `json:"sequencing"`
+}
+
+func (repository *QueryRepository) FetchSequencedCases() ([]SequencedCase, error) {
+	rows, err := repository.source.QueryRows("sequencedcases")
+	if err != nil {
+		return nil, err
+	}
+
+	var result []SequencedCase
+	for _, row := range rows {
+		result = append(result, SequencedCase{
+			Fallnummer:  row.Value("fallnummer"),
+			SampleId:    row.Value("einsendenummer"),
+			SampleDate:  row.Value("entnahmedatum"),
+			ConsentDate: row.Value("consent_date"),
+			Sequencing:  metadata.Type(row.Value("consent_sequencing")),
+		})
+	}
+	return result, nil
+}
+
+func (repository *FixtureRepository) FetchSequencedCases() ([]SequencedCase, error) {
+	var result []SequencedCase
+	for _, c := range repository.Cases {
+		for _, fallnummer := range c.Fallnummern {
+			sequencedCase := SequencedCase{
+				Fallnummer: fallnummer,
+				SampleId:   c.SampleId,
+				SampleDate: c.SampleDate,
+			}
+			if consentMv, ok := repository.MvConsents[fallnummer]; ok {
+				if scope := findScope(consentMv, metadata.MvSequencing); scope != nil {
+					sequencedCase.ConsentDate = scope.Date
+					sequencedCase.Sequencing = scope.Type
+				}
+			}
+			result = append(result, sequencedCase)
+		}
+	}
+	return result, nil
+}
+
+// exportedCases returns all combinations of case ID and sample ID already exported
+func exportedCases(history []HistoryEntry) map[[2]string]bool {
+	result := map[[2]string]bool{}
+	for _, entry := range history {
+		result[[2]string{entry.Fallnummer, entry.SampleId}] = true
+	}
+	return result
+}
+
+type PendingCmd struct {
+	Csv bool `help:"Ausgabe als CSV"`
+}
+
+func (cmd *PendingCmd) Run(globals *Globals, repository Repository) error {
+	sequencedCases, err := repository.FetchSequencedCases()
+	if err != nil {
+		return err
+	}
+	history, err := readHistory(globals.History)
+	if err != nil {
+		return fmt.Errorf("cannot read export history: %w", err)
+	}
+
+	exported := exportedCases(history)
+	var pending []SequencedCase
+	for _, c := range sequencedCases {
+		if c.Sequencing == metadata.Permit && !exported[[2]string{c.Fallnummer, c.SampleId}] {
+			pending = append(pending, c)
+		}
+	}
+
+	out := io.Writer(os.Stdout)
+	if len(globals.Filename) > 0 {
+		f, err := os.Create(globals.Filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if cmd.Csv {
+		return writePendingCsv(out, pending)
+	}
+
+	if len(pending) == 0 {
+		_, _ = fmt.Fprintln(out, "Keine ausstehenden Einreichungen gefunden")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "Fallnummer\tEinsendenummer\tEntnahmedatum\tMV-Consent")
+	for _, c := range pending {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Fallnummer, c.SampleId, c.SampleDate, c.ConsentDate)
+	}
+	return w.Flush()
+}
+
+func writePendingCsv(out io.Writer, pending []SequencedCase) error {
+	w := csv.NewWriter(out)
+	_ = w.Write([]string{"fallnummer", "einsendenummer", "entnahmedatum", "consent_datum"})
+	for _, c := range pending {
+		_ = w.Write([]string{c.Fallnummer, c.SampleId, c.SampleDate, c.ConsentDate})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPending(t *testing.T) {
+	header := "fallnummer,einsendenummer,entnahmedatum,consent_datum\n"
+	tests := []struct {
+		name       string
+		history    []HistoryEntry
+		statements []string
+		expected   string
+	}{
+		{
+			name:     "without history",
+			expected: header + "FALL-2025-0001,H/2025/0001,2025-03-12,2025-02-20\n",
+		},
+		{
+			name:     "exported case",
+			history:  []HistoryEntry{{Kind: "export", Fallnummer: "FALL-2025-0001", SampleId: "H/2025/0001"}},
+			expected: header,
+		},
+		{
+			name: "other sample of exported case",
+			history: []HistoryEntry{
+				{Kind: "export", Fallnummer: "FALL-2025-0001", SampleId: "H/2024/0123"},
+				{Kind: "export", Fallnummer: "FALL-2024-0001", SampleId: "H/2025/0001"},
+			},
+			expected: header + "FALL-2025-0001,H/2025/0001,2025-03-12,2025-02-20\n",
+		},
+		{
+			name:       "denied MV consent",
+			statements: []string{"UPDATE dk_dnpm_uf_consentmvverlauf SET sequencing = 'deny'"},
+			expected:   header,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := onkostarDb(t)
+			for _, statement := range test.statements {
+				if _, err := db.Exec(statement); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dir := t.TempDir()
+			globals := &Globals{
+				History:  filepath.Join(dir, "history.jsonl"),
+				Filename: filepath.Join(dir, "pending.csv"),
+			}
+			for _, entry := range test.history {
+				if err := appendHistory(globals.History, entry); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cmd := &PendingCmd{Csv: true}
+			if err := cmd.Run(globals, NewQueryRepository(NewMySqlSource(db, ""), map[string]ValueMapping{}, TissueMapping{})); err != nil {
+				t.Fatal(err)
+			}
+
+			content, err := os.ReadFile(globals.Filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, string(content))
+			}
+		})
+	}
+}
@@ -3,6 +3,11 @@ package main
 import (
 	_ "embed"
 	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
 )
 
 type Klinik struct {
@@ -40,11 +45,46 @@ type Profile struct {
 	BioinformaticsPipelineVersion string `json:"bioinformaticsPipelineVersion"`
 	CallerUsedName                string `json:"callerUsedName"`
 	CallerUsedVersion             string `json:"callerUsedVersion"`
+	// TargetedRegionsFile is the BED file of the capture kit relative to the files directory
+	TargetedRegionsFile string  `json:"targetedRegionsFile"`
+	NonCodingVariants   bool    `json:"nonCodingVariants"`
+	MinCoverage         float64 `json:"minCoverage"`
+	// Rna contains LabData settings for RNA sequenced from the same sample, if any
+	Rna *Profile `json:"rna,omitempty"`
+}
+
+// ForSequenceType returns the profile to be used for lab data of the sequence type or nil if not available
+func (profile *Profile) ForSequenceType(sequenceType metadata.SequenceType) *Profile {
+	if sequenceType == metadata.Rna && profile.Rna != nil {
+		return profile.Rna
+	}
+	if len(sequenceType) == 0 || strings.EqualFold(profile.SequenceType, string(sequenceType)) {
+		return profile
+	}
+	return nil
 }
 
 //go:embed profiles.json
 var profiles []byte
 
+// LoadProfiles replaces the embedded profiles with the given file, if any.
+// An invalid file results in an error instead of using the embedded profiles.
+func LoadProfiles(filename string) error {
+	if len(filename) == 0 {
+		return nil
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("cannot read profiles: %w", err)
+	}
+	var kliniken []Klinik
+	if err := json.Unmarshal(content, &kliniken); err != nil {
+		return fmt.Errorf("cannot read profiles '%s': %w", filename, err)
+	}
+	profiles = content
+	return nil
+}
+
 func ReadProfiles() []Klinik {
 	var result []Klinik
 	if err := json.Unmarshal(profiles, &result); err != nil {
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+func TestLoadProfiles(t *testing.T) {
+	embedded := profiles
+	t.Cleanup(func() { profiles = embedded })
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "profiles.json", `[{
+		"ik": "123456789",
+		"name": "Test",
+		"profiles": [{"name": "Nanopore WGS", "sequenceType": "DNA", "libraryType": "wgs_lr", "sequencingLayout": "single-end"}]
+	}]`)
+	if err := LoadProfiles(filepath.Join(dir, "profiles.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	if FindProfile("260960079", "UKW - OCAplus (CCC-Patho)") != nil {
+		t.Error("expected embedded profiles to be replaced")
+	}
+	profile := FindProfile("123456789", "Nanopore WGS")
+	if profile == nil {
+		t.Fatal("expected profile from file")
+	}
+	data := &metadata.Metadata{Donors: []metadata.Donor{{LabData: []metadata.LabDatum{{SequenceType: metadata.Dna}}}}}
+	applyProfile(data, profile)
+	if labData := data.Donors[0].LabData[0]; labData.LibraryType != metadata.WgsLr || len(checkLongReadLabData(data)) > 0 {
+		t.Errorf("expected valid long-read lab data, got '%s' and %v", labData.LibraryType, checkLongReadLabData(data))
+	}
+}
+
+func TestLoadProfilesWithInvalidFile(t *testing.T) {
+	embedded := profiles
+	t.Cleanup(func() { profiles = embedded })
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "profiles.json", `{"ik": "123456789"}`)
+	if err := LoadProfiles(filepath.Join(dir, "profiles.json")); err == nil {
+		t.Error("expected error for invalid profiles file")
+	}
+	if FindProfile("260960079", "UKW - OCAplus (CCC-Patho)") == nil {
+		t.Error("expected embedded profiles to be kept")
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// readPurity returns the bioinformatic tumor cell count estimated by PURPLE ('*.purple.purity.tsv')
+// or Sequenza ('*_alternative_solutions.txt'). The first result row contains the best estimate.
+func readPurity(filename string) (*metadata.TumorCellCount, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.Comma = '\t'
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read purity file '%s': %w", filename, err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("purity file '%s' contains no estimate", filename)
+	}
+
+	for idx, column := range records[0] {
+		column = strings.Trim(column, "\" ")
+		// PURPLE uses 'purity', Sequenza 'cellularity', both as fraction
+		if column != "purity" && column != "cellularity" {
+			continue
+		}
+		if idx >= len(records[1]) {
+			break
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(records[1][idx]), 64)
+		if err != nil || value < 0 || value > 1 {
+			return nil, fmt.Errorf("purity file '%s' contains invalid %s '%s'", filename, column, records[1][idx])
+		}
+		return &metadata.TumorCellCount{
+			Count:  math.Round(value*1000) / 10,
+			Method: metadata.Bioinformatics,
+		}, nil
+	}
+	return nil, fmt.Errorf("purity file '%s' contains no column 'purity' or 'cellularity'", filename)
+}
+
+// addTumorCellCount adds the tumor cell count to the first DNA lab datum or replaces one with the same method
+func addTumorCellCount(data *metadata.Metadata, tumorCellCount metadata.TumorCellCount) {
+	if len(data.Donors) == 0 {
+		return
+	}
+	idx := slices.IndexFunc(data.Donors[0].LabData, func(labData metadata.LabDatum) bool {
+		return labData.SequenceType != metadata.Rna
+	})
+	if idx < 0 {
+		return
+	}
+	labData := &data.Donors[0].LabData[idx]
+	for idx := range labData.TumorCellCount {
+		if labData.TumorCellCount[idx].Method == tumorCellCount.Method {
+			labData.TumorCellCount[idx] = tumorCellCount
+			return
+		}
+	}
+	labData.TumorCellCount = append(labData.TumorCellCount, tumorCellCount)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+//go:embed queries/*.sql
+var queries embed.FS
+
+// queryNames contains the names of all queries used to fetch Onkostar data
+var queryNames = []string{"metadata", "fallnummern", "mvconsent", "cases", "sequencedcases", "topography"}
+
+// loadQuery returns the query with given name from query directory, if present, or the default query
+func loadQuery(queryDir string, name string) (string, error) {
+	filename := name + ".sql"
+	if len(queryDir) > 0 {
+		if content, err := os.ReadFile(filepath.Join(queryDir, filename)); err == nil {
+			return string(content), nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	content, err := queries.ReadFile("queries/" + filename)
+	return string(content), err
+}
+
+// queryArgs uses a single value for each placeholder in query, multiple values are used in given order
+// and must match the number of placeholders
+func queryArgs(query string, values ...string) ([]any, error) {
+	placeholders := countPlaceholders(query)
+	var args []any
+	if len(values) == 1 {
+		for range placeholders {
+			args = append(args, values[0])
+		}
+		return args, nil
+	}
+	if placeholders != len(values) {
+		return nil, fmt.Errorf("query contains %d placeholders, expected %d", placeholders, len(values))
+	}
+	for _, value := range values {
+		args = append(args, value)
+	}
+	return args, nil
+}
+
+// countPlaceholders returns the number of '?' placeholders in query.
+// Question marks in string literals, quoted identifiers and comments are not counted.
+func countPlaceholders(query string) int {
+	count := 0
+	for idx := 0; idx < len(query); idx++ {
+		switch c := query[idx]; {
+		case c == '?':
+			count++
+		case c == '\'' || c == '"' || c == '`':
+			for idx++; idx < len(query) && query[idx] != c; idx++ {
+				if query[idx] == '\\' && c != '`' {
+					idx++
+				}
+			}
+		case c == '#' || strings.HasPrefix(query[idx:], "-- "):
+			if end := strings.IndexByte(query[idx:], '\n'); end >= 0 {
+				idx += end
+			} else {
+				idx = len(query)
+			}
+		case strings.HasPrefix(query[idx:], "/*"):
+			if end := strings.Index(query[idx+2:], "*/"); end >= 0 {
+				idx += end + 3
+			} else {
+				idx = len(query)
+			}
+		}
+	}
+	return count
+}
+
+// Row contains the values of a result row by lower case column name, nil for NULL values
+type Row map[string]*string
+
+// Value returns the value of the column or an empty string for NULL values
+func (row Row) Value(column string) string {
+	if value := row[column]; value != nil {
+		return *value
+	}
+	return ""
+}
+
+// scanRow returns the values of the current row by column name
+func scanRow(rows *sql.Rows) (Row, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for idx := range values {
+		dest[idx] = &values[idx]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+	result := Row{}
+	for idx, column := range columns {
+		if values[idx].Valid {
+			result[strings.ToLower(column)] = &values[idx].String
+		} else {
+			result[strings.ToLower(column)] = nil
+		}
+	}
+	return result, nil
+}
+
+// errInvalidValue is returned for column values that cannot be converted into the type of the target field
+var errInvalidValue = errors.New("invalid value")
+
+// columnRanges contains the valid range of numeric columns
+var columnRanges = map[string][2]float64{
+	// Tumor cell count in percent
+	"donors_items_labdata_items_tumorcellcount_items_count": {0, 100},
+}
+
+// setColumnValue sets the value for a column name like 'donors_items_labdata_items_sampledate'.
+// Each name part matches a JSON property name, 'items' refers to the last item of a list.
+func setColumnValue(target any, column string, value string) error {
+	if valueRange, ok := columnRanges[column]; ok {
+		if f, err := parseNumber(value); err == nil && (f < valueRange[0] || f > valueRange[1]) {
+			return fmt.Errorf("column '%s': %w '%s' (not within %g and %g)", column, errInvalidValue, value, valueRange[0], valueRange[1])
+		}
+	}
+	if err := setValue(reflect.ValueOf(target).Elem(), strings.Split(column, "_"), value); err != nil {
+		return fmt.Errorf("column '%s': %w", column, err)
+	}
+	return nil
+}
+
+// parseNumber accepts percentages and decimal commas, e.g. '40 %' or '40,5'
+func parseNumber(value string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "%")), ",", "."), 64)
+}
+
+func setValue(target reflect.Value, path []string, value string) error {
+	switch target.Kind() {
+	case reflect.Pointer:
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+		return setValue(target.Elem(), path, value)
+	case reflect.Struct:
+		if len(path) == 0 {
+			return fmt.Errorf("incomplete path")
+		}
+		for idx := range target.NumField() {
+			name, _, _ := strings.Cut(target.Type().Field(idx).Tag.Get("json"), ",")
+			if strings.EqualFold(name, path[0]) {
+				return setValue(target.Field(idx), path[1:], value)
+			}
+		}
+		return fmt.Errorf("unknown property '%s'", path[0])
+	case reflect.Slice:
+		if len(path) == 0 || path[0] != "items" {
+			return fmt.Errorf("missing 'items' for list")
+		}
+		if target.Len() == 0 {
+			// Only add an item if the value can be set
+			item := reflect.New(target.Type().Elem()).Elem()
+			if err := setValue(item, path[1:], value); err != nil {
+				return err
+			}
+			target.Set(reflect.Append(target, item))
+			return nil
+		}
+		return setValue(target.Index(target.Len()-1), path[1:], value)
+	}
+
+	if len(path) > 0 {
+		return fmt.Errorf("unknown property '%s'", path[0])
+	}
+
+	switch target.Kind() {
+	case reflect.String:
+		target.SetString(value)
+	case reflect.Float64:
+		f, err := parseNumber(value)
+		if err != nil {
+			return fmt.Errorf("%w '%s' (not a number)", errInvalidValue, value)
+		}
+		target.SetFloat(f)
+	case reflect.Int64:
+		i, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w '%s' (not an integer)", errInvalidValue, value)
+		}
+		target.SetInt(i)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%w '%s' (not a boolean)", errInvalidValue, value)
+		}
+		target.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type %s", target.Type())
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+SELECT
+    dk_molekulargenetik.einsendenummer AS einsendenummer,
+    dk_molekulargenetik.entnahmedatum AS entnahmedatum,
+    patient.patienten_id AS patienten_id,
+    prop_probenmaterial.shortdesc AS probenmaterial,
+    prop_nukleinsaeure.shortdesc AS nukleinsaeure,
+    dk_molekulargenetik.artdersequenzierung AS artdersequenzierung
+FROM dk_molekulargenetik
+JOIN (SELECT ? AS patienten_id, ? AS date_from, ? AS date_to, ? AS fallnummer) AS filter
+JOIN prozedur ON (prozedur.id = dk_molekulargenetik.id)
+JOIN patient ON (patient.id = prozedur.patient_id)
+LEFT JOIN property_catalogue_version_entry AS prop_nukleinsaeure ON (
+    prop_nukleinsaeure.property_version_id = dk_molekulargenetik.nukleinsaeure_propcat_version
+        AND prop_nukleinsaeure.code = dk_molekulargenetik.nukleinsaeure)
+LEFT JOIN property_catalogue_version_entry AS prop_probenmaterial ON (
+    prop_probenmaterial.property_version_id = dk_molekulargenetik.probenmaterial_propcat_version
+        AND prop_probenmaterial.code = dk_molekulargenetik.probenmaterial)
+WHERE dk_molekulargenetik.einsendenummer IS NOT NULL
+    AND (filter.patienten_id = '' OR patient.patienten_id = filter.patienten_id)
+    AND (filter.date_from = '' OR dk_molekulargenetik.entnahmedatum >= filter.date_from)
+    AND (filter.date_to = '' OR dk_molekulargenetik.entnahmedatum <= filter.date_to)
+    AND (filter.fallnummer = '' OR dk_molekulargenetik.id IN (
+        SELECT ref_molekulargenetik FROM (
+            SELECT ref_molekulargenetik, id FROM dk_dnpm_uf_rebiopsie
+            UNION SELECT ref_molekulargenetik, id FROM dk_dnpm_uf_reevaluation
+            UNION SELECT ref_molekulargenetik, id FROM dk_dnpm_uf_einzelempfehlung
+        ) AS unterformular
+        JOIN prozedur ON (prozedur.id = unterformular.id)
+        JOIN dk_dnpm_therapieplan ON (dk_dnpm_therapieplan.id = prozedur.hauptprozedur_id)
+        JOIN dk_dnpm_kpa ON (dk_dnpm_kpa.id = dk_dnpm_therapieplan.ref_dnpm_klinikanamnese)
+        WHERE dk_dnpm_kpa.fallnummermv = filter.fallnummer
+    ))
+ORDER BY dk_molekulargenetik.entnahmedatum, dk_molekulargenetik.einsendenummer
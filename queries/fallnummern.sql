@@ -0,0 +1,22 @@
+SELECT DISTINCT
+    dk_dnpm_kpa.fallnummermv AS fallnummer
+FROM dk_dnpm_kpa
+JOIN dk_dnpm_therapieplan ON (dk_dnpm_therapieplan.ref_dnpm_klinikanamnese = dk_dnpm_kpa.id AND dk_dnpm_kpa.fallnummermv = ?)
+OR dk_dnpm_therapieplan.id IN (
+    SELECT hauptprozedur_id FROM dk_molekulargenetik
+    JOIN dk_dnpm_uf_rebiopsie ON (dk_dnpm_uf_rebiopsie.ref_molekulargenetik = dk_molekulargenetik.id)
+    JOIN prozedur ON (prozedur.id = dk_dnpm_uf_rebiopsie.id)
+    WHERE einsendenummer = ?
+)
+OR dk_dnpm_therapieplan.id IN (
+    SELECT hauptprozedur_id FROM dk_molekulargenetik
+    JOIN dk_dnpm_uf_reevaluation ON (dk_dnpm_uf_reevaluation.ref_molekulargenetik = dk_molekulargenetik.id)
+    JOIN prozedur ON (prozedur.id = dk_dnpm_uf_reevaluation.id)
+    WHERE einsendenummer = ?
+)
+OR dk_dnpm_therapieplan.id IN (
+    SELECT hauptprozedur_id FROM dk_molekulargenetik
+    JOIN dk_dnpm_uf_einzelempfehlung ON (dk_dnpm_uf_einzelempfehlung.ref_molekulargenetik = dk_molekulargenetik.id)
+    JOIN prozedur ON (prozedur.id = dk_dnpm_uf_einzelempfehlung.id)
+    WHERE einsendenummer = ?
+)
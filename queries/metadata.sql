@@ -0,0 +1,29 @@
+SELECT
+    organisationunit.identifier AS submission_labname,
+    kostentraegertyp AS submission_coveragetype,
+    patient.patienten_id AS donors_items_donorpseudonym,
+    patient.geschlecht AS donors_items_gender,
+    CONCAT(prop_probenmaterial.shortdesc, ' ', prop_nukleinsaeure.shortdesc) AS donors_items_labdata_items_labdataname,
+    dk_molekulargenetik.entnahmedatum AS donors_items_labdata_items_sampledate,
+    dk_molekulargenetik.materialfixierung AS donors_items_labdata_items_sampleconservation,
+    LOWER(prop_nukleinsaeure.shortdesc) AS donors_items_labdata_items_sequencetype,
+    dk_molekulargenetik.artdersequenzierung AS donors_items_labdata_items_librarytype,
+    dk_molekulargenetik.tumorzellgehalt AS donors_items_labdata_items_tumorcellcount_items_count,
+    dk_molekulargenetik.referenzgenom AS donors_items_labdata_items_sequencedata_referencegenome,
+    dk_molekulargenetik.panel AS x_panel, # Use this to select default Kit info
+    dk_molekulargenetik.probenmaterial AS x_probenmaterial # Use this to select the tissue type
+FROM dk_molekulargenetik
+JOIN prozedur ON (prozedur.id = dk_molekulargenetik.id)
+JOIN patient ON (patient.id = prozedur.patient_id)
+LEFT JOIN organisationunit ON (organisationunit.id = dk_molekulargenetik.durchfuehrendeoe_fachabteilung)
+LEFT JOIN property_catalogue_version_entry AS prop_nukleinsaeure ON (
+    prop_nukleinsaeure.property_version_id = dk_molekulargenetik.nukleinsaeure_propcat_version
+        AND prop_nukleinsaeure.code = dk_molekulargenetik.nukleinsaeure)
+LEFT JOIN property_catalogue_version_entry AS prop_probenmaterial ON (
+    prop_probenmaterial.property_version_id = dk_molekulargenetik.probenmaterial_propcat_version
+        AND prop_probenmaterial.code = dk_molekulargenetik.probenmaterial)
+
+# Hier die Einsendenummer aus Rohdaten-Datei in diesem Format einfügen
+WHERE dk_molekulargenetik.entnahmedatum IS NOT NULL AND einsendenummer = ?
+# Stable order of DNA and RNA of the same sample
+ORDER BY dk_molekulargenetik.entnahmedatum, prop_nukleinsaeure.shortdesc, dk_molekulargenetik.id
@@ -0,0 +1,16 @@
+SELECT
+    date,
+    version,
+    sequencing,
+    caseidentification,
+    reidentification
+FROM dk_dnpm_uf_consentmvverlauf
+JOIN prozedur ON (prozedur.id = dk_dnpm_uf_consentmvverlauf.id)
+WHERE prozedur.hauptprozedur_id IN (
+    SELECT dk_dnpm_consentmv.id
+    FROM dk_dnpm_kpa
+    JOIN dk_dnpm_consentmv ON (dk_dnpm_consentmv.id = dk_dnpm_kpa.consentmv64e)
+    WHERE fallnummermv = ?
+)
+ORDER BY dk_dnpm_uf_consentmvverlauf.date DESC
+LIMIT 1
@@ -0,0 +1,31 @@
+SELECT DISTINCT
+    dk_dnpm_kpa.fallnummermv AS fallnummer,
+    dk_molekulargenetik.einsendenummer AS einsendenummer,
+    dk_molekulargenetik.entnahmedatum AS entnahmedatum,
+    consent.date AS consent_date,
+    consent.sequencing AS consent_sequencing
+FROM dk_dnpm_kpa
+JOIN dk_dnpm_therapieplan ON (dk_dnpm_therapieplan.ref_dnpm_klinikanamnese = dk_dnpm_kpa.id)
+JOIN prozedur AS unterformular_prozedur ON (unterformular_prozedur.hauptprozedur_id = dk_dnpm_therapieplan.id)
+JOIN (
+    SELECT ref_molekulargenetik, id FROM dk_dnpm_uf_rebiopsie
+    UNION SELECT ref_molekulargenetik, id FROM dk_dnpm_uf_reevaluation
+    UNION SELECT ref_molekulargenetik, id FROM dk_dnpm_uf_einzelempfehlung
+) AS unterformular ON (unterformular.id = unterformular_prozedur.id)
+JOIN dk_molekulargenetik ON (dk_molekulargenetik.id = unterformular.ref_molekulargenetik)
+LEFT JOIN (
+    SELECT prozedur.hauptprozedur_id AS consentmv_id, dk_dnpm_uf_consentmvverlauf.date, dk_dnpm_uf_consentmvverlauf.sequencing
+    FROM dk_dnpm_uf_consentmvverlauf
+    JOIN prozedur ON (prozedur.id = dk_dnpm_uf_consentmvverlauf.id)
+) AS consent ON (
+    consent.consentmv_id = dk_dnpm_kpa.consentmv64e
+        AND consent.date = (
+            SELECT MAX(dk_dnpm_uf_consentmvverlauf.date)
+            FROM dk_dnpm_uf_consentmvverlauf
+            JOIN prozedur ON (prozedur.id = dk_dnpm_uf_consentmvverlauf.id)
+            WHERE prozedur.hauptprozedur_id = dk_dnpm_kpa.consentmv64e
+        )
+)
+WHERE dk_dnpm_kpa.fallnummermv IS NOT NULL
+    AND dk_molekulargenetik.einsendenummer IS NOT NULL
+ORDER BY dk_molekulargenetik.entnahmedatum, dk_dnpm_kpa.fallnummermv
@@ -0,0 +1,10 @@
+SELECT
+    dk_diagnose.icdo3_lokalisation AS topography
+FROM dk_molekulargenetik
+JOIN prozedur ON (prozedur.id = dk_molekulargenetik.id)
+JOIN prozedur AS diagnose ON (diagnose.patient_id = prozedur.patient_id)
+JOIN dk_diagnose ON (dk_diagnose.id = diagnose.id)
+WHERE dk_molekulargenetik.einsendenummer = ?
+    AND dk_diagnose.icdo3_lokalisation IS NOT NULL
+ORDER BY dk_diagnose.diagnosedatum DESC
+LIMIT 1
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+func TestSetColumnValueTumorCellCount(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected float64
+		invalid  bool
+	}{
+		{"40", 40, false},
+		{"40,5 %", 40.5, false},
+		{"0", 0, false},
+		{"100", 100, false},
+		{"-1", 0, true},
+		{"100.1", 0, true},
+		{"400", 0, true},
+		{"viel", 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			data := metadata.Metadata{}
+			err := setColumnValue(&data, "donors_items_labdata_items_tumorcellcount_items_count", test.value)
+			if test.invalid {
+				if !errors.Is(err, errInvalidValue) {
+					t.Errorf("expected invalid value, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count := data.Donors[0].LabData[0].TumorCellCount; len(count) != 1 || count[0].Count != test.expected {
+				t.Errorf("expected tumor cell count %g, got %v", test.expected, count)
+			}
+		})
+	}
+}
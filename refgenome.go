@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// referenceContigLengths contains lengths of contigs differing between reference genomes
+var referenceContigLengths = map[metadata.ReferenceGenome]map[string]int64{
+	metadata.GRCh37: {"1": 249250621, "2": 243199373, "X": 155270560},
+	metadata.GRCh38: {"1": 248956422, "2": 242193529, "X": 156040895},
+}
+
+// maxHeaderSize limits the size of BAM and CRAM headers read, since sizes are taken from the file
+const maxHeaderSize = 64 << 20
+
+// checkHeaderSize returns an error for negative sizes or sizes exceeding maxHeaderSize
+func checkHeaderSize(size int64, filename string) error {
+	if size < 0 || size > maxHeaderSize {
+		return fmt.Errorf("invalid header size %d in '%s'", size, filename)
+	}
+	return nil
+}
+
+// detectReferenceGenome returns the reference genome matching the contig lengths.
+// Contig names with and without 'chr' prefix are supported.
+func detectReferenceGenome(contigs map[string]int64) metadata.ReferenceGenome {
+	for referenceGenome, lengths := range referenceContigLengths {
+		for name, length := range contigs {
+			if lengths[strings.TrimPrefix(name, "chr")] == length {
+				return referenceGenome
+			}
+		}
+	}
+	return ""
+}
+
+// samHeaderContigs returns the contig lengths of '@SQ' lines in a SAM header
+func samHeaderContigs(header string) map[string]int64 {
+	result := map[string]int64{}
+	for _, line := range strings.Split(header, "\n") {
+		if !strings.HasPrefix(line, "@SQ") {
+			continue
+		}
+		name, length := "", int64(0)
+		for _, field := range strings.Split(line, "\t")[1:] {
+			if value, ok := strings.CutPrefix(field, "SN:"); ok {
+				name = value
+			} else if value, ok := strings.CutPrefix(field, "LN:"); ok {
+				length, _ = strconv.ParseInt(value, 10, 64)
+			}
+		}
+		result[name] = length
+	}
+	return result
+}
+
+// readAlignmentContigs returns the contig lengths of a BAM or CRAM file
+func readAlignmentContigs(filename string) (map[string]int64, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	magic, err := reader.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read '%s': %w", filename, err)
+	}
+	if string(magic) == "CRAM" {
+		return readCramContigs(reader, filename)
+	}
+
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("file '%s' is neither BAM nor CRAM", filename)
+	}
+	var header struct {
+		Magic [4]byte
+		Size  int32
+	}
+	if err := binary.Read(gz, binary.LittleEndian, &header); err != nil || string(header.Magic[:]) != "BAM\x01" {
+		return nil, fmt.Errorf("file '%s' is not a BAM file", filename)
+	}
+	if err := checkHeaderSize(int64(header.Size), filename); err != nil {
+		return nil, err
+	}
+	text := make([]byte, header.Size)
+	if _, err := io.ReadFull(gz, text); err != nil {
+		return nil, fmt.Errorf("cannot read BAM header of '%s': %w", filename, err)
+	}
+	return samHeaderContigs(string(text)), nil
+}
+
+// readCramContigs reads the SAM header contained in the first block of the first container of a CRAM file
+func readCramContigs(reader *bufio.Reader, filename string) (map[string]int64, error) {
+	definition := make([]byte, 26)
+	if _, err := io.ReadFull(reader, definition); err != nil {
+		return nil, fmt.Errorf("cannot read CRAM header of '%s': %w", filename, err)
+	}
+	majorVersion := definition[4]
+
+	// Container header: length, reference sequence id, start, span, records, record counter, bases,
+	// number of blocks, landmarks and CRC32 since CRAM 3
+	var length int32
+	if err := binary.Read(reader, binary.LittleEndian, &length); err != nil {
+		return nil, fmt.Errorf("cannot read CRAM header of '%s': %w", filename, err)
+	}
+	for range 4 {
+		readItf8(reader)
+	}
+	if majorVersion >= 3 {
+		readLtf8(reader)
+		readLtf8(reader)
+	} else {
+		readItf8(reader)
+		readLtf8(reader)
+	}
+	readItf8(reader)
+	for range readItf8(reader) {
+		readItf8(reader)
+	}
+	if majorVersion >= 3 {
+		_, _ = reader.Discard(4)
+	}
+
+	// Block: compression method, content type, content id, compressed and raw size
+	method, _ := reader.ReadByte()
+	_, _ = reader.ReadByte()
+	readItf8(reader)
+	compressedSize := readItf8(reader)
+	readItf8(reader)
+	if err := checkHeaderSize(int64(compressedSize), filename); err != nil {
+		return nil, err
+	}
+	data := make([]byte, compressedSize)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, fmt.Errorf("cannot read CRAM header of '%s': %w", filename, err)
+	}
+
+	var content io.Reader = bytes.NewReader(data)
+	switch method {
+	case 0:
+	case 1:
+		gz, err := gzip.NewReader(content)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CRAM header of '%s': %w", filename, err)
+		}
+		content = gz
+	default:
+		return nil, fmt.Errorf("unsupported compression of CRAM header in '%s'", filename)
+	}
+
+	var size int32
+	if err := binary.Read(content, binary.LittleEndian, &size); err != nil {
+		return nil, fmt.Errorf("cannot read CRAM header of '%s': %w", filename, err)
+	}
+	if err := checkHeaderSize(int64(size), filename); err != nil {
+		return nil, err
+	}
+	text := make([]byte, size)
+	if _, err := io.ReadFull(content, text); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("cannot read CRAM header of '%s': %w", filename, err)
+	}
+	return samHeaderContigs(string(text)), nil
+}
+
+// readItf8 reads a CRAM ITF8 encoded integer
+func readItf8(reader *bufio.Reader) int32 {
+	first, _ := reader.ReadByte()
+	switch {
+	case first&0x80 == 0:
+		return int32(first)
+	case first&0x40 == 0:
+		b, _ := reader.ReadByte()
+		return int32(first&0x3f)<<8 | int32(b)
+	case first&0x20 == 0:
+		b := make([]byte, 2)
+		_, _ = io.ReadFull(reader, b)
+		return int32(first&0x1f)<<16 | int32(b[0])<<8 | int32(b[1])
+	case first&0x10 == 0:
+		b := make([]byte, 3)
+		_, _ = io.ReadFull(reader, b)
+		return int32(first&0x0f)<<24 | int32(b[0])<<16 | int32(b[1])<<8 | int32(b[2])
+	default:
+		b := make([]byte, 4)
+		_, _ = io.ReadFull(reader, b)
+		return int32(first&0x0f)<<28 | int32(b[0])<<20 | int32(b[1])<<12 | int32(b[2])<<4 | int32(b[3]&0x0f)
+	}
+}
+
+// readLtf8 skips a CRAM LTF8 encoded integer, since its value is not used
+func readLtf8(reader *bufio.Reader) {
+	first, _ := reader.ReadByte()
+	extra := 0
+	for mask := byte(0x80); mask != 0 && first&mask != 0; mask >>= 1 {
+		extra++
+	}
+	_, _ = reader.Discard(extra)
+}
+
+// checkReferenceGenome compares the reference genome of each lab datum with the contigs of its BAM/CRAM and VCF files
+// in filesDir and returns all mismatches and files that cannot be read
+func checkReferenceGenome(data *metadata.Metadata, filesDir string) (mismatches []string, warnings []string) {
+	if len(data.Donors) == 0 {
+		return nil, nil
+	}
+	for idx, labData := range data.Donors[0].LabData {
+		if labData.SequenceData == nil {
+			continue
+		}
+		for _, file := range labData.SequenceData.Files {
+			var contigs map[string]int64
+			var err error
+			switch file.FileType {
+			case metadata.BAM:
+				contigs, err = readAlignmentContigs(filepath.Join(filesDir, file.FilePath))
+			case metadata.Vcf:
+				var header *VcfHeader
+				if header, err = readVcfHeader(filepath.Join(filesDir, file.FilePath)); err == nil {
+					contigs = header.Contigs
+				}
+			default:
+				continue
+			}
+			// Missing files are reported when creating the submission directory
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			} else if err != nil {
+				warnings = append(warnings, fmt.Sprintf("labData[%d]: %s", idx, err.Error()))
+				continue
+			}
+
+			referenceGenome := detectReferenceGenome(contigs)
+			if len(referenceGenome) > 0 && referenceGenome != labData.SequenceData.ReferenceGenome {
+				mismatches = append(mismatches, fmt.Sprintf("labData[%d]: '%s' uses %s, but reference genome is '%s'", idx, file.FilePath, referenceGenome, labData.SequenceData.ReferenceGenome))
+			}
+		}
+	}
+	return mismatches, warnings
+}
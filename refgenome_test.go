@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// writeBam writes a BAM file containing only magic and header size
+func writeBam(t *testing.T, size int32) string {
+	t.Helper()
+	var content bytes.Buffer
+	gz := gzip.NewWriter(&content)
+	_, _ = gz.Write([]byte("BAM\x01"))
+	_ = binary.Write(gz, binary.LittleEndian, size)
+	_ = gz.Close()
+
+	filename := filepath.Join(t.TempDir(), "sample.bam")
+	if err := os.WriteFile(filename, content.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestReadAlignmentContigsWithInvalidHeaderSize(t *testing.T) {
+	for _, size := range []int32{-1, maxHeaderSize + 1} {
+		if _, err := readAlignmentContigs(writeBam(t, size)); err == nil || !strings.Contains(err.Error(), "invalid header size") {
+			t.Errorf("expected error for header size %d, got %v", size, err)
+		}
+	}
+}
+
+func TestCheckReferenceGenome(t *testing.T) {
+	filesDir := t.TempDir()
+	vcf := "##fileformat=VCFv4.2\n##contig=<ID=chr1,length=248956422>\n#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\n"
+	if err := os.WriteFile(filepath.Join(filesDir, "sample.vcf"), []byte(vcf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := &metadata.Metadata{
+		Donors: []metadata.Donor{
+			{
+				LabData: []metadata.LabDatum{
+					{
+						SequenceData: &metadata.SequenceData{
+							ReferenceGenome: metadata.GRCh37,
+							Files:           []metadata.File{{FilePath: "sample.vcf", FileType: metadata.Vcf}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	mismatches, warnings := checkReferenceGenome(data, filesDir)
+	if len(mismatches) != 1 || len(warnings) > 0 {
+		t.Errorf("expected one mismatch, got %v and warnings %v", mismatches, warnings)
+	}
+
+	data.Donors[0].LabData[0].SequenceData.ReferenceGenome = metadata.GRCh38
+	if mismatches, _ := checkReferenceGenome(data, filesDir); len(mismatches) > 0 {
+		t.Errorf("expected no mismatch, got %v", mismatches)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	_ "embed"
+	"html/template"
+	"os"
+	"time"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+//go:embed templates/report.html
+var reportTemplate string
+
+// ReportData contains all information shown in a submission report
+type ReportData struct {
+	Created  string
+	SampleId string
+	Klinik   *Klinik
+	Profile  *Profile
+	Grz      string
+	Kdk      string
+	// SchemaVersion is the selected version of the GRZ metadata schema, if any
+	SchemaVersion string
+	Metadata      *metadata.Metadata
+}
+
+func writeReport(filename string, request MetadataRequest, data *metadata.Metadata, schemaVersion string) error {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"deref": func(value *string) string {
+			if value == nil {
+				return ""
+			}
+			return *value
+		},
+	}).Parse(reportTemplate)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	err = tmpl.Execute(f, ReportData{
+		Created:       time.Now().Format("02.01.2006 15:04"),
+		SampleId:      request.SampleId,
+		Klinik:        FindKlinik(request.Ik),
+		Profile:       FindProfile(request.Ik, request.Profile),
+		Grz:           optionLabel(genomicDataCenters, data.Submission.GenomicDataCenterID),
+		Kdk:           optionLabel(clinicalDataNodes, data.Submission.ClinicalDataNodeID),
+		SchemaVersion: schemaVersion,
+		Metadata:      data,
+	})
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
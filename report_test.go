@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+func TestWriteReportWithSchemaVersion(t *testing.T) {
+	data := &metadata.Metadata{
+		Donors:     []metadata.Donor{{DonorPseudonym: "P000001", Gender: metadata.Female}},
+		Submission: metadata.Submission{SubmissionType: metadata.Initial, LabName: "PATHO"},
+	}
+	converted, _ := testSchemaConverter(t).Convert(data)
+	j, _ := json.Marshal(converted)
+	reported, err := metadata.UnmarshalMetadata(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		data          *metadata.Metadata
+		schemaVersion string
+		contains      []string
+		excludes      []string
+	}{
+		{
+			name:     "current schema",
+			data:     data,
+			contains: []string{"P000001", "female"},
+			excludes: []string{"Version des Metadatenschemas"},
+		},
+		{
+			name:          "converted metadata",
+			data:          &reported,
+			schemaVersion: "0.0.1",
+			contains:      []string{"P000001", "Version des Metadatenschemas</th><td>0.0.1"},
+			excludes:      []string{"female"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "report.html")
+			if err := writeReport(filename, MetadataRequest{SampleId: "H/2025/0001"}, test.data, test.schemaVersion); err != nil {
+				t.Fatal(err)
+			}
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, value := range test.contains {
+				if !strings.Contains(string(content), value) {
+					t.Errorf("expected report to contain '%s'", value)
+				}
+			}
+			for _, value := range test.excludes {
+				if strings.Contains(string(content), value) {
+					t.Errorf("expected report not to contain '%s'", value)
+				}
+			}
+		})
+	}
+}
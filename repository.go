@@ -0,0 +1,289 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// Repository provides access to Onkostar data
+type Repository interface {
+	// FetchMetadata returns metadata for the sample including the MV consent of the case
+	FetchMetadata(sampleId string, fallnummer string) (*metadata.Metadata, error)
+	// FetchFallnummern returns all case IDs related to the sample
+	FetchFallnummern(sampleId string) ([]string, error)
+	// FetchMvConsent returns the latest MV consent of the case, if any
+	FetchMvConsent(fallnummer string) (*metadata.MvConsent, error)
+	// FetchCases returns all samples matching the filter
+	FetchCases(filter CaseFilter) ([]Case, error)
+	// FetchSequencedCases returns all sequenced samples linked to a case
+	FetchSequencedCases() ([]SequencedCase, error)
+}
+
+// RowSource provides result rows of the queries in the queries directory
+type RowSource interface {
+	// QueryRows returns all rows of the named query using the given parameters
+	QueryRows(name string, params ...string) ([]Row, error)
+}
+
+// QueryRepository maps the results of Onkostar queries onto metadata
+type QueryRepository struct {
+	source   RowSource
+	mappings map[string]ValueMapping
+	tissues  TissueMapping
+}
+
+// NewQueryRepository uses rows of the source, Onkostar codes are mapped using given value and tissue mappings
+func NewQueryRepository(source RowSource, mappings map[string]ValueMapping, tissues TissueMapping) *QueryRepository {
+	return &QueryRepository{
+		source:   source,
+		mappings: mappings,
+		tissues:  tissues,
+	}
+}
+
+func (repository *QueryRepository) FetchMetadata(sampleId string, fallnummer string) (*metadata.Metadata, error) {
+	rows, err := repository.source.QueryRows("metadata", sampleId)
+	if err != nil {
+		return nil, err
+	}
+
+	var result = metadata.Metadata{}
+	topography := repository.FetchTopography(sampleId)
+
+	for _, columns := range rows {
+		row := metadata.Metadata{
+			Submission: metadata.Submission{
+				SubmissionType: metadata.Initial,
+				DiseaseType:    metadata.Oncological,
+			},
+			Donors: []metadata.Donor{
+				{
+					LabData: []metadata.LabDatum{
+						{
+							Barcode: "NA",
+							// Missing values in Onkostar result in no tumor cell count instead of a count of 0
+							TumorCellCount: []metadata.TumorCellCount{},
+							SequenceData: &metadata.SequenceData{
+								Files: []metadata.File{},
+							},
+						},
+					},
+					// Onkostar only holds index patient data
+					Relation: metadata.Index,
+				},
+			},
+		}
+
+		for column, value := range columns {
+			// Columns prefixed with 'x_' are not mapped and might be used in later processing
+			if strings.HasPrefix(column, "x_") {
+				continue
+			}
+			if _, ok := repository.mappings[column]; ok {
+				mapped, ok := mapValue(repository.mappings, column, columns.Value(column))
+				if !ok {
+					log.Printf("Warning: No mapping for code '%s' in column '%s' of sample '%s', using '%s'\n", columns.Value(column), column, sampleId, mapped)
+				}
+				value = &mapped
+				if len(mapped) == 0 {
+					value = nil
+				}
+			}
+			if value == nil {
+				continue
+			}
+			if err := setColumnValue(&row, column, *value); errors.Is(err, errInvalidValue) {
+				log.Printf("Warning: Ignoring %s in sample '%s'\n", err.Error(), sampleId)
+			} else if err != nil {
+				return nil, err
+			}
+		}
+
+		// Tumor cell counts in Onkostar are estimated by pathology, might be overwritten by profile
+		for idx := range row.Donors[0].LabData[0].TumorCellCount {
+			row.Donors[0].LabData[0].TumorCellCount[idx].Method = metadata.Pathology
+		}
+
+		// The tumor localisation only applies to tumor material, e.g. not to blood samples used as germline
+		tissue := repository.tissues.Find(columns.Value("x_probenmaterial"), topography)
+		if tissue == nil {
+			log.Printf("Warning: No tissue type for Probenmaterial '%s' in sample '%s'\n", columns.Value("x_probenmaterial"), sampleId)
+		}
+		applyTissue(&row.Donors[0].LabData[0], repository.tissues, tissue)
+
+		if len(result.Donors) == 0 {
+			result = row
+			// Without case ID there is no MV consent
+			if len(fallnummer) > 0 {
+				consentMv, err := repository.FetchMvConsent(fallnummer)
+				if err != nil {
+					return nil, err
+				}
+				if consentMv != nil {
+					result.Donors[0].MvConsent = *consentMv
+				}
+			}
+		} else {
+			result.Donors[0].LabData = append(result.Donors[0].LabData, row.Donors[0].LabData...)
+		}
+	}
+
+	return &result, nil
+}
+
+func (repository *QueryRepository) FetchFallnummern(sampleId string) ([]string, error) {
+	rows, err := repository.source.QueryRows("fallnummern", sampleId)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, row := range rows {
+		result = append(result, row.Value("fallnummer"))
+	}
+	return result, nil
+}
+
+// FetchTopography returns the ICD-O-3 topography of the latest diagnosis of the patient, if any.
+// Errors are reported as warning only, since the diagnosis is not required for the export.
+func (repository *QueryRepository) FetchTopography(sampleId string) string {
+	rows, err := repository.source.QueryRows("topography", sampleId)
+	if err != nil {
+		log.Printf("Warning: Cannot fetch ICD-O-3 topography of sample '%s': %s\n", sampleId, err.Error())
+		return ""
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+	return rows[0].Value("topography")
+}
+
+func (repository *QueryRepository) FetchMvConsent(caseId string) (*metadata.MvConsent, error) {
+	rows, err := repository.source.QueryRows("mvconsent", caseId)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	date := rows[0].Value("date")
+	mvConsent := metadata.MvConsent{
+		PresentationDate: &date,
+		Version:          rows[0].Value("version"),
+		Scope: []metadata.Scope{
+			{
+				Type:   metadata.Type(rows[0].Value("sequencing")),
+				Date:   date,
+				Domain: metadata.MvSequencing,
+			},
+			{
+				Type:   metadata.Type(rows[0].Value("reidentification")),
+				Date:   date,
+				Domain: metadata.ReIdentification,
+			},
+			{
+				Type:   metadata.Type(rows[0].Value("caseidentification")),
+				Date:   date,
+				Domain: metadata.CaseIdentification,
+			},
+		},
+	}
+
+	return &mvConsent, nil
+}
+
+// MySqlSource uses an Onkostar database
+type MySqlSource struct {
+	db *sql.DB
+	// queryDir contains site-specific queries replacing the default queries, if any
+	queryDir string
+}
+
+func NewMySqlSource(db *sql.DB, queryDir string) *MySqlSource {
+	return &MySqlSource{db: db, queryDir: queryDir}
+}
+
+func (source *MySqlSource) QueryRows(name string, params ...string) ([]Row, error) {
+	query, err := loadQuery(source.queryDir, name)
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := queryArgs(query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("query '%s': %w", name, err)
+	}
+	rows, err := source.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Row{}
+	for rows.Next() {
+		row, err := scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// FixtureRepository uses fixed data read from a JSON file, e.g. for testing or demonstration
+type FixtureRepository struct {
+	Metadata    map[string]metadata.Metadata  `json:"metadata"`
+	Fallnummern map[string][]string           `json:"fallnummern"`
+	MvConsents  map[string]metadata.MvConsent `json:"mvConsents"`
+	Cases       []Case                        `json:"cases"`
+}
+
+func NewFixtureRepository(filename string) (*FixtureRepository, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var result FixtureRepository
+	if err := json.Unmarshal(content, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repository *FixtureRepository) FetchMetadata(sampleId string, fallnummer string) (*metadata.Metadata, error) {
+	data, ok := repository.Metadata[sampleId]
+	if !ok {
+		return &metadata.Metadata{}, nil
+	}
+
+	// Use a deep copy to keep fixture data unchanged
+	var result metadata.Metadata
+	j, _ := json.Marshal(data)
+	if err := json.Unmarshal(j, &result); err != nil {
+		return nil, err
+	}
+
+	if consentMv, err := repository.FetchMvConsent(fallnummer); err == nil && consentMv != nil && len(result.Donors) > 0 {
+		result.Donors[0].MvConsent = *consentMv
+	}
+	return &result, nil
+}
+
+func (repository *FixtureRepository) FetchFallnummern(sampleId string) ([]string, error) {
+	return repository.Fallnummern[sampleId], nil
+}
+
+func (repository *FixtureRepository) FetchMvConsent(fallnummer string) (*metadata.MvConsent, error) {
+	if consentMv, ok := repository.MvConsents[fallnummer]; ok {
+		return &consentMv, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+)
+
+func stringPtr(value string) *string {
+	return &value
+}
+
+func TestFixtureRepositoryUsesMvConsentOfCase(t *testing.T) {
+	data, err := fixtureRepository(t).FetchMetadata("H/2025/0001", "FALL-2025-0001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "mvconsent-fixture", data.Donors[0].MvConsent)
+}
+
+func TestFixtureRepositoryWithoutCase(t *testing.T) {
+	data, err := fixtureRepository(t).FetchMetadata("H/2025/0001", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consent := data.Donors[0].MvConsent; len(consent.Scope) > 0 || consent.PresentationDate != nil {
+		t.Errorf("expected no MV consent without case, got %v", consent)
+	}
+}
+
+func TestQueryRepositoryMapsMvConsent(t *testing.T) {
+	source := &ExtractSource{
+		extract: Extract{
+			Results: map[string]map[string][]Row{
+				"mvconsent": {
+					"FALL-2025-0001": {
+						{
+							"date":               stringPtr("2025-02-20"),
+							"version":            stringPtr("Patienteninformation und Einwilligung MVGenomSeq vers01"),
+							"sequencing":         stringPtr("permit"),
+							"caseidentification": stringPtr("deny"),
+							"reidentification":   stringPtr("permit"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	consent, err := NewQueryRepository(source, nil, TissueMapping{}).FetchMvConsent("FALL-2025-0001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Same consent as in fixture
+	assertGolden(t, "mvconsent-fixture", consent)
+}
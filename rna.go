@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// checkRnaLabData returns messages for RNA lab data not meeting RNA specific requirements:
+// RNA is not sequenced as germline and only FASTQ files are submitted
+func checkRnaLabData(data *metadata.Metadata) []string {
+	var result []string
+	if len(data.Donors) == 0 {
+		return result
+	}
+	for idx, labData := range data.Donors[0].LabData {
+		if labData.SequenceType != metadata.Rna {
+			continue
+		}
+		if labData.SequenceSubtype == metadata.Germline {
+			result = append(result, fmt.Sprintf("labData[%d]: RNA cannot use sequence subtype '%s'", idx, labData.SequenceSubtype))
+		}
+		if labData.SequenceData == nil {
+			continue
+		}
+		for _, file := range labData.SequenceData.Files {
+			if file.FileType != metadata.Fastq {
+				result = append(result, fmt.Sprintf("labData[%d]: RNA file '%s' is not a FASTQ file", idx, file.FilePath))
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+func TestCheckRnaLabData(t *testing.T) {
+	tests := []struct {
+		name     string
+		labData  metadata.LabDatum
+		expected []string
+	}{
+		{
+			name: "somatic RNA with FASTQ files",
+			labData: metadata.LabDatum{
+				SequenceType:    metadata.Rna,
+				SequenceSubtype: metadata.Somatic,
+				SequenceData:    &metadata.SequenceData{Files: []metadata.File{{FilePath: "rna_R1.fastq.gz", FileType: metadata.Fastq}}},
+			},
+		},
+		{
+			name:     "germline RNA",
+			labData:  metadata.LabDatum{SequenceType: metadata.Rna, SequenceSubtype: metadata.Germline},
+			expected: []string{"labData[0]: RNA cannot use sequence subtype 'germline'"},
+		},
+		{
+			name: "RNA with BAM and VCF files",
+			labData: metadata.LabDatum{
+				SequenceType: metadata.Rna,
+				SequenceData: &metadata.SequenceData{
+					Files: []metadata.File{
+						{FilePath: "rna_R1.fastq.gz", FileType: metadata.Fastq},
+						{FilePath: "rna.bam", FileType: metadata.BAM},
+						{FilePath: "rna.vcf", FileType: metadata.Vcf},
+					},
+				},
+			},
+			expected: []string{"labData[0]: RNA file 'rna.bam' is not a FASTQ file", "labData[0]: RNA file 'rna.vcf' is not a FASTQ file"},
+		},
+		{
+			name: "germline DNA with VCF file",
+			labData: metadata.LabDatum{
+				SequenceType:    metadata.Dna,
+				SequenceSubtype: metadata.Germline,
+				SequenceData:    &metadata.SequenceData{Files: []metadata.File{{FilePath: "normal.vcf", FileType: metadata.Vcf}}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := &metadata.Metadata{Donors: []metadata.Donor{{LabData: []metadata.LabDatum{test.labData}}}}
+			actual := checkRnaLabData(data)
+			if len(actual) != len(test.expected) {
+				t.Fatalf("expected %d messages, got %v", len(test.expected), actual)
+			}
+			for idx, message := range test.expected {
+				if actual[idx] != message {
+					t.Errorf("expected message '%s', got '%s'", message, actual[idx])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyProfileSplitsDnaAndRna(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  string
+		expected []string
+	}{
+		{
+			name:     "profile with RNA settings",
+			profile:  "UKW - OCAplus (CCC-Patho)",
+			expected: []string{"Tumor DNA", "Tumor RNA"},
+		},
+		{
+			name:     "profile without RNA settings",
+			profile:  "UKW - Exom (CCC-Patho)",
+			expected: []string{"Tumor DNA", "Tumorgewebe RNA"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := createMetadata(fixtureRepository(t), MetadataRequest{
+				SampleId: "H/2025/0001",
+				Ik:       "260960079",
+				Profile:  test.profile,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			labData := data.Donors[0].LabData
+			if len(labData) != len(test.expected) {
+				t.Fatalf("expected %d lab data, got %d", len(test.expected), len(labData))
+			}
+			for idx, labDataName := range test.expected {
+				if labData[idx].LabDataName != labDataName {
+					t.Errorf("expected lab data name '%s' for lab data %d, got '%s'", labDataName, idx, labData[idx].LabDataName)
+				}
+			}
+			if labData[0].SequenceType != metadata.Dna || labData[1].SequenceType != metadata.Rna {
+				t.Errorf("expected DNA and RNA lab data, got '%s' and '%s'", labData[0].SequenceType, labData[1].SequenceType)
+			}
+			if messages := checkRnaLabData(data); len(messages) > 0 {
+				t.Errorf("expected valid RNA lab data, got %v", messages)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// SampleSheetEntry contains the barcode of a sample in a sequencing run
+type SampleSheetEntry struct {
+	SampleId string
+	Barcode  string
+}
+
+// SampleSheet contains the samples of a sequencing run
+type SampleSheet struct {
+	Flowcell string
+	Entries  []SampleSheetEntry
+}
+
+// readSampleSheet reads an Illumina SampleSheet.csv (v1 and v2) or an Ion Torrent run plan exported as CSV.
+// The flowcell of Illumina runs is read from 'RunInfo.xml' next to the sample sheet, if available.
+func readSampleSheet(filename string) (*SampleSheet, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var records [][]string
+	reader := csv.NewReader(strings.NewReader(string(content)))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if records, err = reader.ReadAll(); err != nil {
+		return nil, fmt.Errorf("cannot read sample sheet '%s': %w", filename, err)
+	}
+
+	result := SampleSheet{}
+	if strings.Contains(string(content), "[Data]") || strings.Contains(string(content), "[BCLConvert_Data]") {
+		result.Entries = illuminaEntries(records)
+		result.Flowcell = readFlowcell(filepath.Join(filepath.Dir(filename), "RunInfo.xml"))
+	} else {
+		result.Entries = ionTorrentEntries(records)
+	}
+	if len(result.Entries) == 0 {
+		return nil, fmt.Errorf("sample sheet '%s' contains no samples", filename)
+	}
+	return &result, nil
+}
+
+// illuminaEntries returns the samples of section '[Data]' (v1) or '[BCLConvert_Data]' (v2).
+// Dual indexes are joined by '-'.
+func illuminaEntries(records [][]string) []SampleSheetEntry {
+	var result []SampleSheetEntry
+	var header []string
+	inData := false
+	for _, record := range records {
+		if len(record) == 0 || len(strings.Join(record, "")) == 0 {
+			continue
+		}
+		if strings.HasPrefix(record[0], "[") {
+			inData = record[0] == "[Data]" || record[0] == "[BCLConvert_Data]"
+			header = nil
+			continue
+		}
+		if !inData {
+			continue
+		}
+		if header == nil {
+			header = record
+			continue
+		}
+
+		values := recordValues(header, record)
+		entry := SampleSheetEntry{
+			SampleId: values["sample_id"],
+			Barcode:  values["index"],
+		}
+		if index2 := values["index2"]; len(index2) > 0 {
+			entry.Barcode = entry.Barcode + "-" + index2
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// ionTorrentEntries returns the samples of a run plan using columns 'Barcode' and 'Sample ID' or 'Sample Name'
+func ionTorrentEntries(records [][]string) []SampleSheetEntry {
+	var result []SampleSheetEntry
+	var header []string
+	for _, record := range records {
+		if header == nil {
+			for _, column := range record {
+				if strings.EqualFold(column, "barcode") {
+					header = record
+					break
+				}
+			}
+			continue
+		}
+
+		values := recordValues(header, record)
+		sampleId := values["sample id"]
+		if len(sampleId) == 0 {
+			sampleId = values["sample name"]
+		}
+		if len(sampleId) == 0 || len(values["barcode"]) == 0 {
+			continue
+		}
+		result = append(result, SampleSheetEntry{SampleId: sampleId, Barcode: values["barcode"]})
+	}
+	return result
+}
+
+// recordValues returns the values of a record by lower case column name
+func recordValues(header []string, record []string) map[string]string {
+	result := map[string]string{}
+	for idx, column := range header {
+		if idx < len(record) {
+			result[strings.ToLower(strings.TrimSpace(column))] = strings.TrimSpace(record[idx])
+		}
+	}
+	return result
+}
+
+// readFlowcell returns the flowcell of an Illumina run or an empty string if 'RunInfo.xml' is not available
+func readFlowcell(filename string) string {
+	f, err := os.Open(filename)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var runInfo struct {
+		Run struct {
+			Flowcell string `xml:"Flowcell"`
+		} `xml:"Run"`
+	}
+	if err := xml.NewDecoder(bufio.NewReader(f)).Decode(&runInfo); err != nil {
+		return ""
+	}
+	return runInfo.Run.Flowcell
+}
+
+var sampleIdSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+
+// normalizeSampleId replaces all separators, since sample sheets do not allow characters like '/' in sample IDs
+func normalizeSampleId(sampleId string) string {
+	return strings.Trim(sampleIdSeparators.ReplaceAllString(strings.ToLower(sampleId), "-"), "-")
+}
+
+// Find returns all samples matching the Einsendenummer, e.g. 'H_2025_0001' or 'H-2025-0001-DNA' for 'H/2025/0001'.
+// Samples sequenced on multiple lanes are returned once.
+func (sampleSheet *SampleSheet) Find(sampleId string) []SampleSheetEntry {
+	var result []SampleSheetEntry
+	found := map[string]bool{}
+	normalized := normalizeSampleId(sampleId)
+	for _, entry := range sampleSheet.Entries {
+		entryId := normalizeSampleId(entry.SampleId)
+		if found[entry.SampleId] || (entryId != normalized && !strings.HasPrefix(entryId, normalized+"-")) {
+			continue
+		}
+		found[entry.SampleId] = true
+		result = append(result, entry)
+	}
+	return result
+}
+
+var illuminaLane = regexp.MustCompile(`_(L\d{3})_`)
+
+// sequenceType returns the sequence type given by the suffix of the sample ID, e.g. 'rna' for 'H-2025-0001-RNA'
+func (entry SampleSheetEntry) sequenceType(sampleId string) metadata.SequenceType {
+	suffix := strings.TrimPrefix(normalizeSampleId(entry.SampleId), normalizeSampleId(sampleId))
+	for _, part := range strings.Split(suffix, "-") {
+		switch part {
+		case "dna":
+			return metadata.Dna
+		case "rna":
+			return metadata.Rna
+		}
+	}
+	return ""
+}
+
+// matchSampleSheetEntries returns the matching sample for each lab datum, nil if there is no sample.
+// Samples match lab data by the sequence type of its suffix, samples without suffix match any lab datum.
+// Each lab datum must match one sample at most and each sample must match one lab datum at most.
+func matchSampleSheetEntries(labData []metadata.LabDatum, entries []SampleSheetEntry, sampleId string) ([]*SampleSheetEntry, error) {
+	result := make([]*SampleSheetEntry, len(labData))
+	matched := map[string]int{}
+	for idx, labDatum := range labData {
+		for entryIdx, entry := range entries {
+			sequenceType := entry.sequenceType(sampleId)
+			if len(sequenceType) > 0 && sequenceType != labDatum.SequenceType {
+				continue
+			}
+			if result[idx] != nil {
+				return nil, fmt.Errorf("samples '%s' and '%s' in sample sheet match lab data %d, use suffix '-DNA' or '-RNA' to distinguish", result[idx].SampleId, entry.SampleId, idx)
+			}
+			if other, ok := matched[entry.SampleId]; ok {
+				return nil, fmt.Errorf("sample '%s' in sample sheet matches lab data %d and %d", entry.SampleId, other, idx)
+			}
+			matched[entry.SampleId] = idx
+			result[idx] = &entries[entryIdx]
+		}
+	}
+	return result, nil
+}
+
+// applySampleSheet sets the barcode of each lab datum using the sample matching its sequence type.
+// Flowcell and lane are set for FASTQ files named after the sample like '<Sample_ID>_S1_L001_R1_001.fastq.gz'.
+func applySampleSheet(data *metadata.Metadata, sampleSheet *SampleSheet, sampleId string) error {
+	if len(data.Donors) == 0 {
+		return nil
+	}
+	entries := sampleSheet.Find(sampleId)
+	if len(entries) == 0 {
+		log.Printf("Warning: No sample matching '%s' in sample sheet\n", sampleId)
+		return nil
+	}
+	matches, err := matchSampleSheetEntries(data.Donors[0].LabData, entries, sampleId)
+	if err != nil {
+		return fmt.Errorf("cannot assign samples of sample sheet: %w", err)
+	}
+
+	for idx, entry := range matches {
+		if entry == nil {
+			log.Printf("Warning: No sample matching '%s' in sample sheet for lab data %d\n", sampleId, idx)
+			continue
+		}
+		labData := &data.Donors[0].LabData[idx]
+		labData.Barcode = entry.Barcode
+
+		if labData.SequenceData == nil {
+			continue
+		}
+		for fileIdx := range labData.SequenceData.Files {
+			file := &labData.SequenceData.Files[fileIdx]
+			name := filepath.Base(file.FilePath)
+			if file.FileType != metadata.Fastq || !strings.HasPrefix(name, entry.SampleId+"_") {
+				continue
+			}
+			if len(sampleSheet.Flowcell) > 0 {
+				flowcell := sampleSheet.Flowcell
+				file.FlowcellID = &flowcell
+			}
+			if match := illuminaLane.FindStringSubmatch(name); match != nil {
+				lane := match[1]
+				file.LaneID = &lane
+			}
+		}
+	}
+	return nil
+}
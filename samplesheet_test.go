@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+func TestApplySampleSheet(t *testing.T) {
+	tests := []struct {
+		name     string
+		entries  []SampleSheetEntry
+		expected []string
+		fails    bool
+	}{
+		{
+			name:     "suffix in reverse order",
+			entries:  []SampleSheetEntry{{"H-2025-0001-RNA", "AAAA"}, {"H-2025-0001-DNA", "CCCC"}},
+			expected: []string{"CCCC", "AAAA"},
+		},
+		{
+			name:     "missing RNA sample",
+			entries:  []SampleSheetEntry{{"H_2025_0001_DNA", "CCCC"}},
+			expected: []string{"CCCC", "NA"},
+		},
+		{
+			name:    "sample without suffix matches both lab data",
+			entries: []SampleSheetEntry{{"H_2025_0001", "CCCC"}},
+			fails:   true,
+		},
+		{
+			name:    "samples without suffix",
+			entries: []SampleSheetEntry{{"H-2025-0001-A", "AAAA"}, {"H-2025-0001-B", "CCCC"}},
+			fails:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := &metadata.Metadata{
+				Donors: []metadata.Donor{
+					{
+						LabData: []metadata.LabDatum{
+							{Barcode: "NA", SequenceType: metadata.Dna},
+							{Barcode: "NA", SequenceType: metadata.Rna},
+						},
+					},
+				},
+			}
+			err := applySampleSheet(data, &SampleSheet{Entries: test.entries}, "H/2025/0001")
+			if test.fails {
+				if err == nil {
+					t.Error("expected error for ambiguous samples")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for idx, barcode := range test.expected {
+				if actual := data.Donors[0].LabData[idx].Barcode; actual != barcode {
+					t.Errorf("expected barcode '%s' for lab data %d, got '%s'", barcode, idx, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestApplySampleSheetWithSingleSample(t *testing.T) {
+	data := &metadata.Metadata{
+		Donors: []metadata.Donor{
+			{
+				LabData: []metadata.LabDatum{
+					{
+						SequenceType: metadata.Dna,
+						SequenceData: &metadata.SequenceData{
+							Files: []metadata.File{
+								{FilePath: "H_2025_0001_S1_L002_R1_001.fastq.gz", FileType: metadata.Fastq},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	sampleSheet := &SampleSheet{Flowcell: "HXXXXXXX", Entries: []SampleSheetEntry{{"H_2025_0001", "CCCC"}, {"H_2025_0002", "AAAA"}}}
+	if err := applySampleSheet(data, sampleSheet, "H/2025/0001"); err != nil {
+		t.Fatal(err)
+	}
+
+	labDatum := data.Donors[0].LabData[0]
+	file := labDatum.SequenceData.Files[0]
+	if labDatum.Barcode != "CCCC" || file.LaneID == nil || *file.LaneID != "L002" || file.FlowcellID == nil || *file.FlowcellID != "HXXXXXXX" {
+		t.Errorf("expected barcode, lane and flowcell of sample sheet, got '%s', %v, %v", labDatum.Barcode, file.LaneID, file.FlowcellID)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// SchemaConverter converts metadata into the structure of a GRZ metadata JSON schema version.
+// Properties not allowed by the schema are removed, missing required properties and invalid values are reported.
+type SchemaConverter struct {
+	version  string
+	root     map[string]any
+	warnings []string
+}
+
+// NewSchemaConverter reads the JSON schema '<version>.json' from the schema directory.
+// Schemas are not bundled, the published schema files of the GRZ have to be placed in the schema directory.
+func NewSchemaConverter(dir string, version string) (*SchemaConverter, error) {
+	content, err := os.ReadFile(filepath.Join(dir, version+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("schema version '%s' not available, place the published GRZ metadata schema as '%s'", version, filepath.Join(dir, version+".json"))
+	} else if err != nil {
+		return nil, fmt.Errorf("schema version '%s' not available in '%s': %w", version, dir, err)
+	}
+	var root map[string]any
+	if err := json.Unmarshal(content, &root); err != nil {
+		return nil, fmt.Errorf("cannot read schema version '%s': %w", version, err)
+	}
+	return &SchemaConverter{version: version, root: root}, nil
+}
+
+// Convert returns the metadata structured according to the schema and warnings about values that cannot be represented
+func (converter *SchemaConverter) Convert(data *metadata.Metadata) (any, []string) {
+	converter.warnings = []string{}
+	j, _ := json.Marshal(data)
+	var value any
+	_ = json.Unmarshal(j, &value)
+	return converter.convert(converter.root, value, "$"), converter.warnings
+}
+
+func (converter *SchemaConverter) warn(format string, args ...any) {
+	converter.warnings = append(converter.warnings, fmt.Sprintf(format, args...))
+}
+
+// resolve follows local references like '#/$defs/Donor' and selects the matching alternative of 'anyOf' or 'oneOf'
+func (converter *SchemaConverter) resolve(schema map[string]any, value any) map[string]any {
+	for range 32 {
+		if ref, ok := schema["$ref"].(string); ok && strings.HasPrefix(ref, "#/") {
+			var target any = converter.root
+			for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+				if object, ok := target.(map[string]any); ok {
+					target = object[part]
+				}
+			}
+			if resolved, ok := target.(map[string]any); ok {
+				schema = resolved
+				continue
+			}
+			return schema
+		}
+
+		alternatives, ok := schema["anyOf"].([]any)
+		if !ok {
+			alternatives, ok = schema["oneOf"].([]any)
+		}
+		if !ok {
+			return schema
+		}
+		selected := schema
+		for _, alternative := range alternatives {
+			if alternativeSchema, ok := alternative.(map[string]any); ok && converter.matchesType(converter.resolve(alternativeSchema, value), value) {
+				selected = alternativeSchema
+				break
+			}
+		}
+		if reflect.DeepEqual(selected, schema) {
+			return schema
+		}
+		schema = selected
+	}
+	return schema
+}
+
+// matchesType checks the JSON type of the value, schemas without type match any value
+func (converter *SchemaConverter) matchesType(schema map[string]any, value any) bool {
+	expected, ok := schema["type"].(string)
+	if !ok {
+		return true
+	}
+	switch value.(type) {
+	case map[string]any:
+		return expected == "object"
+	case []any:
+		return expected == "array"
+	case string:
+		return expected == "string"
+	case float64:
+		return expected == "number" || expected == "integer"
+	case bool:
+		return expected == "boolean"
+	case nil:
+		return expected == "null"
+	}
+	return false
+}
+
+func (converter *SchemaConverter) convert(schema map[string]any, value any, path string) any {
+	if schema == nil {
+		return value
+	}
+	schema = converter.resolve(schema, value)
+
+	switch typed := value.(type) {
+	case map[string]any:
+		properties, ok := schema["properties"].(map[string]any)
+		if !ok {
+			return typed
+		}
+		result := map[string]any{}
+		for _, key := range slices.Sorted(maps.Keys(typed)) {
+			item := typed[key]
+			propertySchema, ok := properties[key].(map[string]any)
+			if !ok && schema["additionalProperties"] == false {
+				converter.warn("%s.%s cannot be represented in schema version %s and is removed", path, key, converter.version)
+				continue
+			}
+			result[key] = converter.convert(propertySchema, item, path+"."+key)
+		}
+		if required, ok := schema["required"].([]any); ok {
+			for _, key := range required {
+				if _, ok := result[key.(string)]; !ok {
+					converter.warn("%s.%s is required by schema version %s", path, key, converter.version)
+				}
+			}
+		}
+		return result
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		result := []any{}
+		for idx, item := range typed {
+			result = append(result, converter.convert(items, item, fmt.Sprintf("%s[%d]", path, idx)))
+		}
+		return result
+	default:
+		if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+			converter.warn("%s value '%v' is not allowed in schema version %s", path, value, converter.version)
+		}
+		return value
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// testSchema is a reduced schema for tests only, it is not a published GRZ metadata schema
+const testSchema = `{
+  "type": "object",
+  "properties": {
+    "submission": { "$ref": "#/$defs/Submission" },
+    "donors": { "type": "array", "items": { "$ref": "#/$defs/Donor" } }
+  },
+  "additionalProperties": false,
+  "$defs": {
+    "Submission": {
+      "type": "object",
+      "properties": {
+        "submissionType": { "type": "string", "enum": ["initial", "followup"] },
+        "tanG": { "type": "string" },
+        "labName": { "type": "string" }
+      },
+      "required": ["submissionType", "labName", "submitterId"],
+      "additionalProperties": false
+    },
+    "Donor": {
+      "anyOf": [
+        { "type": "null" },
+        {
+          "type": "object",
+          "properties": { "donorPseudonym": { "type": "string" } },
+          "additionalProperties": false
+        }
+      ]
+    }
+  }
+}`
+
+func testSchemaConverter(t *testing.T) *SchemaConverter {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "0.0.1.json"), []byte(testSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	converter, err := NewSchemaConverter(dir, "0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return converter
+}
+
+func TestSchemaConverterWithoutSchemaFile(t *testing.T) {
+	_, err := NewSchemaConverter(t.TempDir(), "1.1.9")
+	if err == nil || !strings.Contains(err.Error(), "1.1.9.json") {
+		t.Errorf("expected error naming the missing schema file, got %v", err)
+	}
+}
+
+func TestSchemaConverterConvert(t *testing.T) {
+	data := &metadata.Metadata{
+		Donors:     []metadata.Donor{{DonorPseudonym: "P000001", Gender: metadata.Female}},
+		Submission: metadata.Submission{SubmissionType: metadata.Test, LabName: "PATHO"},
+	}
+	converted, warnings := testSchemaConverter(t).Convert(data)
+
+	document := converted.(map[string]any)
+	donor := document["donors"].([]any)[0].(map[string]any)
+	if _, ok := donor["gender"]; ok || donor["donorPseudonym"] != "P000001" {
+		t.Errorf("expected donor without gender, got %v", donor)
+	}
+	if submission := document["submission"].(map[string]any); submission["labName"] != "PATHO" || len(submission) != 3 {
+		t.Errorf("expected submission properties of schema only, got %v", submission)
+	}
+
+	for _, expected := range []string{
+		"$.donors[0].gender cannot be represented in schema version 0.0.1 and is removed",
+		"$.submission.submissionType value 'test' is not allowed in schema version 0.0.1",
+		"$.submission.submitterId is required by schema version 0.0.1",
+	} {
+		if !slices.Contains(warnings, expected) {
+			t.Errorf("expected warning '%s', got %v", expected, warnings)
+		}
+	}
+}
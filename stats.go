@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// Sources of statistics entries
+const (
+	StatsExport   = "export"
+	StatsOnkostar = "onkostar"
+)
+
+// StatsEntry contains the number of submissions for a combination of quarter and submission properties.
+// Entries of Onkostar contain the number of sequenced samples with MV consent by sample date.
+type StatsEntry struct {
+	Source           string `json:"source"`
+	Quarter          string `json:"quarter"`
+	Grz              string `json:"grz"`
+	Kdk              string `json:"kdk"`
+	Ik               string `json:"ik"`
+	SubmissionType   string `json:"submissionType"`
+	GenomicStudyType string `json:"genomicStudyType"`
+	LibraryType      string `json:"libraryType"`
+	CoverageType     string `json:"coverageType"`
+	Count            int    `json:"count"`
+	// LabData is the number of lab data of this library type in all counted submissions
+	LabData int `json:"labData"`
+}
+
+type StatsCmd struct {
+	Year     int  `help:"Nur Exporte in diesem Jahr berücksichtigen"`
+	Onkostar bool `help:"Zusätzlich sequenzierte Proben mit MV-Consent aus Onkostar zählen"`
+	Json     bool `help:"Ausgabe als JSON" xor:"format"`
+	Csv      bool `help:"Ausgabe als CSV" xor:"format"`
+}
+
+// Run uses a repository provider, since a database connection is only required for Onkostar data
+func (cmd *StatsCmd) Run(globals *Globals, repositoryProvider RepositoryProvider) error {
+	history, err := readHistory(globals.History)
+	if err != nil {
+		return fmt.Errorf("cannot read export history: %w", err)
+	}
+
+	stats := aggregateStats(history, cmd.Year)
+	if cmd.Onkostar {
+		repository, err := repositoryProvider()
+		if err != nil {
+			return err
+		}
+		sequencedCases, err := repository.FetchSequencedCases()
+		if err != nil {
+			return err
+		}
+		stats = append(stats, aggregateSequencedCases(sequencedCases, cmd.Year)...)
+	}
+
+	out := io.Writer(os.Stdout)
+	if len(globals.Filename) > 0 {
+		f, err := os.Create(globals.Filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	switch {
+	case cmd.Json:
+		j, _ := json.MarshalIndent(stats, "", "  ")
+		_, err := fmt.Fprintln(out, string(j))
+		return err
+	case cmd.Csv:
+		return writeStatsCsv(out, stats)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "Quelle\tQuartal\tGRZ\tKDK\tIK\tArt\tStudientyp\tLibrary\tKostenträger\tAnzahl\tLabData")
+	for _, entry := range stats {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\n",
+			entry.Source,
+			entry.Quarter,
+			entry.Grz,
+			entry.Kdk,
+			entry.Ik,
+			entry.SubmissionType,
+			entry.GenomicStudyType,
+			entry.LibraryType,
+			entry.CoverageType,
+			entry.Count,
+			entry.LabData,
+		)
+	}
+	return w.Flush()
+}
+
+func quarter(date time.Time) string {
+	return fmt.Sprintf("%d-Q%d", date.Year(), (int(date.Month())-1)/3+1)
+}
+
+// aggregateStats counts submissions by quarter and submission properties, templates and downloads are no submissions.
+// Repeated submissions of the same sample, case and submission type are counted once using the latest submission.
+// Submissions are counted for each library type of its lab data.
+func aggregateStats(history []HistoryEntry, year int) []StatsEntry {
+	latest := map[[3]string]HistoryEntry{}
+	for _, entry := range history {
+		if entry.Kind == HistorySubmission {
+			latest[[3]string{entry.SampleId, entry.Fallnummer, entry.SubmissionType}] = entry
+		}
+	}
+
+	counts := map[StatsEntry]int{}
+	labData := map[StatsEntry]int{}
+	for _, entry := range latest {
+		date, err := time.Parse(time.RFC3339, entry.Date)
+		if err != nil || (year > 0 && date.Year() != year) {
+			continue
+		}
+		libraryTypes := map[string]int{}
+		for _, libraryType := range entry.LibraryTypes {
+			libraryTypes[libraryType]++
+		}
+		for libraryType, count := range libraryTypes {
+			key := StatsEntry{
+				Source:           StatsExport,
+				Quarter:          quarter(date),
+				Grz:              entry.Grz,
+				Kdk:              entry.Kdk,
+				Ik:               entry.Ik,
+				SubmissionType:   entry.SubmissionType,
+				GenomicStudyType: entry.GenomicStudyType,
+				LibraryType:      libraryType,
+				CoverageType:     entry.CoverageType,
+			}
+			counts[key]++
+			labData[key] += count
+		}
+	}
+
+	return sortedStats(counts, labData)
+}
+
+// aggregateSequencedCases counts sequenced samples with MV consent for sequencing in Onkostar by quarter of sample date
+func aggregateSequencedCases(sequencedCases []SequencedCase, year int) []StatsEntry {
+	samples := map[string]time.Time{}
+	for _, c := range sequencedCases {
+		date, ok := parseDate(c.SampleDate)
+		if c.Sequencing != metadata.Permit || !ok || (year > 0 && date.Year() != year) {
+			continue
+		}
+		samples[c.SampleId] = date
+	}
+
+	counts := map[StatsEntry]int{}
+	for _, date := range samples {
+		counts[StatsEntry{Source: StatsOnkostar, Quarter: quarter(date)}]++
+	}
+	return sortedStats(counts, counts)
+}
+
+func sortedStats(counts map[StatsEntry]int, labData map[StatsEntry]int) []StatsEntry {
+	result := []StatsEntry{}
+	for key, count := range counts {
+		entry := key
+		entry.Count = count
+		entry.LabData = labData[key]
+		result = append(result, entry)
+	}
+	slices.SortFunc(result, func(a, b StatsEntry) int {
+		return strings.Compare(
+			strings.Join([]string{a.Source, a.Quarter, a.Grz, a.Kdk, a.Ik, a.SubmissionType, a.GenomicStudyType, a.LibraryType, a.CoverageType}, "|"),
+			strings.Join([]string{b.Source, b.Quarter, b.Grz, b.Kdk, b.Ik, b.SubmissionType, b.GenomicStudyType, b.LibraryType, b.CoverageType}, "|"),
+		)
+	})
+	return result
+}
+
+func writeStatsCsv(out io.Writer, stats []StatsEntry) error {
+	w := csv.NewWriter(out)
+	_ = w.Write([]string{"quelle", "quartal", "grz", "kdk", "ik", "submission_type", "genomic_study_type", "library_type", "coverage_type", "anzahl", "labdata"})
+	for _, entry := range stats {
+		_ = w.Write([]string{
+			entry.Source,
+			entry.Quarter,
+			entry.Grz,
+			entry.Kdk,
+			entry.Ik,
+			entry.SubmissionType,
+			entry.GenomicStudyType,
+			entry.LibraryType,
+			entry.CoverageType,
+			strconv.Itoa(entry.Count),
+			strconv.Itoa(entry.LabData),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
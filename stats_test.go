@@ -0,0 +1,44 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAggregateStatsCountsSubmissionsOnly(t *testing.T) {
+	history := []HistoryEntry{
+		{Kind: HistorySubmission, Date: "2025-02-01T10:00:00Z", SampleId: "H/2025/0001", Fallnummer: "FALL-2025-0001", SubmissionType: "initial", LibraryTypes: []string{"panel", "panel_lr", "panel"}},
+		// Repeated submission is counted once
+		{Kind: HistorySubmission, Date: "2025-02-02T10:00:00Z", SampleId: "H/2025/0001", Fallnummer: "FALL-2025-0001", SubmissionType: "initial", LibraryTypes: []string{"panel", "panel"}},
+		{Kind: HistoryTemplate, Date: "2025-02-01T10:00:00Z", SampleId: "H/2025/0002", SubmissionType: "initial", LibraryTypes: []string{"wes"}},
+		{Kind: HistoryDownload, Date: "2025-02-01T10:00:00Z", SampleId: "H/2025/0003", SubmissionType: "initial", LibraryTypes: []string{"wes"}},
+		// Legacy entries without kind
+		{Date: "2025-02-01T10:00:00Z", SampleId: "H/2025/0004", SubmissionType: "initial"},
+		{Kind: HistorySubmission, Date: "2024-12-01T10:00:00Z", SampleId: "H/2024/0001", SubmissionType: "initial", LibraryTypes: []string{"wes"}},
+	}
+
+	expected := []StatsEntry{
+		{Source: StatsExport, Quarter: "2025-Q1", SubmissionType: "initial", LibraryType: "panel", Count: 1, LabData: 2},
+	}
+	if stats := aggregateStats(history, 2025); !slices.Equal(stats, expected) {
+		t.Errorf("expected %v, got %v", expected, stats)
+	}
+}
+
+func TestAggregateSequencedCases(t *testing.T) {
+	sequencedCases := []SequencedCase{
+		{Fallnummer: "FALL-2025-0001", SampleId: "H/2025/0001", SampleDate: "2025-03-12", Sequencing: "permit"},
+		{Fallnummer: "FALL-2025-0002", SampleId: "H/2025/0001", SampleDate: "2025-03-12", Sequencing: "permit"},
+		{Fallnummer: "FALL-2025-0003", SampleId: "H/2025/0002", SampleDate: "2025-04-01 08:30:00", Sequencing: "permit"},
+		{Fallnummer: "FALL-2025-0004", SampleId: "H/2025/0003", SampleDate: "2025-04-02", Sequencing: "deny"},
+		{Fallnummer: "FALL-2025-0005", SampleId: "H/2025/0004", SampleDate: "", Sequencing: "permit"},
+	}
+
+	expected := []StatsEntry{
+		{Source: StatsOnkostar, Quarter: "2025-Q1", Count: 1, LabData: 1},
+		{Source: StatsOnkostar, Quarter: "2025-Q2", Count: 1, LabData: 1},
+	}
+	if stats := aggregateSequencedCases(sequencedCases, 2025); !slices.Equal(stats, expected) {
+		t.Errorf("expected %v, got %v", expected, stats)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// writeSubmission creates the submission directory layout expected by grz-cli.
+// All files referenced in metadata are linked (or copied) from filesDir into the submission.
+// The content of 'metadata.json' is given as already marshalled JSON.
+func writeSubmission(data *metadata.Metadata, content []byte, submissionDir string, filesDir string, copyFiles bool) error {
+	if err := verifyFiles(data, filesDir); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Join(submissionDir, "metadata"), 0755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(submissionDir, "files"), 0755); err != nil {
+		return err
+	}
+
+	for _, file := range sequenceFiles(data) {
+		source, err := filepath.Abs(filepath.Join(filesDir, file.FilePath))
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(submissionDir, "files", file.FilePath)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if _, err := os.Lstat(target); err == nil {
+			if err := os.Remove(target); err != nil {
+				return err
+			}
+		}
+		if copyFiles {
+			err = copyFile(source, target)
+		} else {
+			err = os.Symlink(source, target)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(filepath.Join(submissionDir, "metadata", "metadata.json"), content, 0644)
+}
+
+// verifyFiles checks that each referenced file exists in filesDir and matches declared size and checksum.
+// File paths must be relative and must not leave filesDir, since they are used for the submission directory as well.
+func verifyFiles(data *metadata.Metadata, filesDir string) error {
+	var errs []error
+	for _, file := range sequenceFiles(data) {
+		if !filepath.IsLocal(file.FilePath) {
+			errs = append(errs, fmt.Errorf("file path '%s' must be relative and within the files directory", file.FilePath))
+			continue
+		}
+		path := filepath.Join(filesDir, file.FilePath)
+		info, err := os.Stat(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("file '%s' not found", file.FilePath))
+			continue
+		}
+		if float64(info.Size()) != file.FileSizeInBytes {
+			errs = append(errs, fmt.Errorf("file '%s' has size %d, expected %.0f", file.FilePath, info.Size(), file.FileSizeInBytes))
+			continue
+		}
+		if file.ChecksumType != nil && *file.ChecksumType != metadata.Sha256 {
+			errs = append(errs, fmt.Errorf("file '%s' uses unsupported checksum type '%s'", file.FilePath, *file.ChecksumType))
+			continue
+		}
+		if checksum, err := sha256File(path); err != nil {
+			errs = append(errs, err)
+		} else if checksum != file.FileChecksum {
+			errs = append(errs, fmt.Errorf("file '%s' has checksum %s, expected %s", file.FilePath, checksum, file.FileChecksum))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func sequenceFiles(data *metadata.Metadata) []metadata.File {
+	var result []metadata.File
+	for _, donor := range data.Donors {
+		for _, labData := range donor.LabData {
+			if labData.SequenceData != nil {
+				result = append(result, labData.SequenceData.Files...)
+			}
+		}
+	}
+	return result
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func copyFile(source string, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+func submissionMetadata(files ...metadata.File) *metadata.Metadata {
+	return &metadata.Metadata{
+		Donors: []metadata.Donor{
+			{LabData: []metadata.LabDatum{{SequenceData: &metadata.SequenceData{Files: files}}}},
+		},
+	}
+}
+
+func submissionFilesDir(t *testing.T) string {
+	t.Helper()
+	filesDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(filesDir, "run1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filesDir, "run1/sample_R1.fastq", "ACGT\n")
+	return filesDir
+}
+
+func TestVerifyFiles(t *testing.T) {
+	filesDir := submissionFilesDir(t)
+	checksum, err := sha256File(filepath.Join(filesDir, "run1/sample_R1.fastq"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	absolute := filepath.Join(filesDir, "run1/sample_R1.fastq")
+	md5 := metadata.ChecksumType("md5")
+
+	tests := []struct {
+		name    string
+		file    metadata.File
+		message string
+	}{
+		{
+			name: "matching file",
+			file: metadata.File{FilePath: "run1/sample_R1.fastq", FileSizeInBytes: 5, FileChecksum: checksum},
+		},
+		{
+			name:    "parent directory",
+			file:    metadata.File{FilePath: "../sample_R1.fastq", FileSizeInBytes: 5, FileChecksum: checksum},
+			message: "must be relative and within the files directory",
+		},
+		{
+			name:    "path leaving files directory",
+			file:    metadata.File{FilePath: "run1/../../sample_R1.fastq", FileSizeInBytes: 5, FileChecksum: checksum},
+			message: "must be relative and within the files directory",
+		},
+		{
+			name:    "absolute path",
+			file:    metadata.File{FilePath: absolute, FileSizeInBytes: 5, FileChecksum: checksum},
+			message: "must be relative and within the files directory",
+		},
+		{
+			name:    "missing file",
+			file:    metadata.File{FilePath: "run1/sample_R2.fastq", FileSizeInBytes: 5, FileChecksum: checksum},
+			message: "file 'run1/sample_R2.fastq' not found",
+		},
+		{
+			name:    "size mismatch",
+			file:    metadata.File{FilePath: "run1/sample_R1.fastq", FileSizeInBytes: 6, FileChecksum: checksum},
+			message: "has size 5, expected 6",
+		},
+		{
+			name:    "checksum mismatch",
+			file:    metadata.File{FilePath: "run1/sample_R1.fastq", FileSizeInBytes: 5, FileChecksum: strings.Repeat("0", 64)},
+			message: "has checksum " + checksum,
+		},
+		{
+			name:    "unsupported checksum type",
+			file:    metadata.File{FilePath: "run1/sample_R1.fastq", FileSizeInBytes: 5, FileChecksum: checksum, ChecksumType: &md5},
+			message: "unsupported checksum type 'md5'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := verifyFiles(submissionMetadata(test.file), filesDir)
+			if len(test.message) == 0 {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.message) {
+				t.Errorf("expected error '%s', got %v", test.message, err)
+			}
+		})
+	}
+}
+
+func TestVerifyFilesReportsAllErrors(t *testing.T) {
+	filesDir := submissionFilesDir(t)
+	err := verifyFiles(submissionMetadata(
+		metadata.File{FilePath: "../sample_R1.fastq"},
+		metadata.File{FilePath: "run1/sample_R2.fastq"},
+	), filesDir)
+	if err == nil || strings.Count(err.Error(), "\n") != 1 {
+		t.Errorf("expected two errors, got %v", err)
+	}
+}
+
+func TestWriteSubmission(t *testing.T) {
+	for _, copyFiles := range []bool{false, true} {
+		name := "link"
+		if copyFiles {
+			name = "copy"
+		}
+		t.Run(name, func(t *testing.T) {
+			// Relative files directory, links must use absolute paths anyway
+			filesDir := submissionFilesDir(t)
+			t.Chdir(filepath.Dir(filesDir))
+			filesDir = filepath.Base(filesDir)
+			checksum, _ := sha256File(filepath.Join(filesDir, "run1/sample_R1.fastq"))
+			data := submissionMetadata(metadata.File{FilePath: "run1/sample_R1.fastq", FileSizeInBytes: 5, FileChecksum: checksum})
+			submissionDir := filepath.Join(t.TempDir(), "submission")
+
+			// Writing twice replaces existing files of a previous submission
+			for range 2 {
+				if err := writeSubmission(data, []byte("{}"), submissionDir, filesDir, copyFiles); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if content, err := os.ReadFile(filepath.Join(submissionDir, "metadata", "metadata.json")); err != nil || string(content) != "{}" {
+				t.Errorf("expected metadata file, got '%s' (%v)", content, err)
+			}
+			target := filepath.Join(submissionDir, "files", "run1", "sample_R1.fastq")
+			info, err := os.Lstat(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isLink := info.Mode()&os.ModeSymlink != 0; isLink == copyFiles {
+				t.Errorf("expected symbolic link %t, got mode %s", !copyFiles, info.Mode())
+			}
+			if !copyFiles {
+				if source, _ := os.Readlink(target); !filepath.IsAbs(source) {
+					t.Errorf("expected absolute link target, got '%s'", source)
+				}
+			}
+			if content, err := os.ReadFile(target); err != nil || string(content) != "ACGT\n" {
+				t.Errorf("expected file content, got '%s' (%v)", content, err)
+			}
+		})
+	}
+}
+
+func TestWriteSubmissionWithInvalidFile(t *testing.T) {
+	filesDir := submissionFilesDir(t)
+	data := submissionMetadata(metadata.File{FilePath: "run1/sample_R1.fastq", FileSizeInBytes: 6})
+	submissionDir := filepath.Join(t.TempDir(), "submission")
+
+	if err := writeSubmission(data, []byte("{}"), submissionDir, filesDir, false); err == nil {
+		t.Fatal("expected error for invalid file")
+	}
+	if _, err := os.Stat(submissionDir); !os.IsNotExist(err) {
+		t.Errorf("expected no submission directory, got %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// BedRegion is a region of a BED file using 0-based start and exclusive end
+type BedRegion struct {
+	Chrom string
+	Start int64
+	End   int64
+	// Values contains all further columns
+	Values []string
+}
+
+// readBedRegions reads all regions of a plain or compressed BED file
+func readBedRegions(filename string) ([]BedRegion, error) {
+	f, err := openFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var result []BedRegion
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(strings.TrimSpace(line)) == 0 || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "track") || strings.HasPrefix(line, "browser") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("invalid BED line in '%s': %s", filename, line)
+		}
+		start, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BED line in '%s': %s", filename, line)
+		}
+		end, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BED line in '%s': %s", filename, line)
+		}
+		result = append(result, BedRegion{Chrom: fields[0], Start: start, End: end, Values: fields[3:]})
+	}
+	return result, scanner.Err()
+}
+
+// readMeanCoverage returns the mean coverage by region read from the last column of mosdepth output ('*.regions.bed.gz')
+func readMeanCoverage(coverage []BedRegion) (map[string]float64, error) {
+	result := map[string]float64{}
+	for _, region := range coverage {
+		if len(region.Values) == 0 {
+			return nil, fmt.Errorf("coverage file contains no mean coverage")
+		}
+		value, err := strconv.ParseFloat(region.Values[len(region.Values)-1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("coverage file contains invalid mean coverage '%s'", region.Values[len(region.Values)-1])
+		}
+		result[region.key()] = value
+	}
+	return result, nil
+}
+
+func (region BedRegion) key() string {
+	return fmt.Sprintf("%s:%d-%d", region.Chrom, region.Start, region.End)
+}
+
+// missingRegions returns the number of targeted regions without mean coverage
+func missingRegions(targets []BedRegion, meanCoverage map[string]float64) int {
+	result := 0
+	for _, region := range targets {
+		if _, ok := meanCoverage[region.key()]; !ok {
+			result++
+		}
+	}
+	return result
+}
+
+// targetedRegionsAboveMinCoverage returns the fraction of targeted bases in regions with a mean coverage of at least
+// minCoverage. Coverage must be created by mosdepth for the same BED file.
+// Targeted regions missing in the coverage file are counted as below minimum coverage.
+func targetedRegionsAboveMinCoverage(targets []BedRegion, meanCoverage map[string]float64, minCoverage float64) (float64, error) {
+	var total, above int64
+	for _, region := range targets {
+		length := region.End - region.Start
+		total += length
+		if value, ok := meanCoverage[region.key()]; ok && value >= minCoverage {
+			above += length
+		}
+	}
+	if missing := missingRegions(targets, meanCoverage); missing > 0 {
+		log.Printf("Warning: %d targeted regions not found in coverage file\n", missing)
+	}
+	if total == 0 {
+		return 0, fmt.Errorf("no targeted regions")
+	}
+	return float64(above) / float64(total), nil
+}
+
+// isTargeted returns true for library types using targeted regions of an enrichment kit
+func isTargeted(libraryType metadata.LibraryType) bool {
+	switch libraryType {
+	case metadata.Panel, metadata.PanelLr, metadata.Wes, metadata.WesLr:
+		return true
+	}
+	return false
+}
+
+// addTargetedRegions adds the BED file of targeted regions, relative to filesDir, to the files of each panel or WES
+// lab datum using the profile for its sequence type, e.g. the RNA profile for RNA lab data.
+// If a coverage file is given, the fraction of targeted regions above minimum coverage is set for the lab datum
+// whose targeted regions are all contained in the coverage file.
+func addTargetedRegions(data *metadata.Metadata, profile *Profile, filesDir string, coverageFile string) error {
+	if len(data.Donors) == 0 {
+		return nil
+	}
+
+	targets := map[int][]BedRegion{}
+	for idx := range data.Donors[0].LabData {
+		labData := &data.Donors[0].LabData[idx]
+		labDataProfile := profile.ForSequenceType(labData.SequenceType)
+		if !isTargeted(labData.LibraryType) || labDataProfile == nil || len(labDataProfile.TargetedRegionsFile) == 0 {
+			continue
+		}
+		if err := addBedFile(labData, labDataProfile.TargetedRegionsFile, filesDir); err != nil {
+			return err
+		}
+		if len(coverageFile) == 0 {
+			continue
+		}
+		regions, err := readBedRegions(filepath.Join(filesDir, labDataProfile.TargetedRegionsFile))
+		if err != nil {
+			return err
+		}
+		targets[idx] = regions
+	}
+
+	if len(coverageFile) == 0 {
+		return nil
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("coverage requires a profile with targeted regions")
+	}
+	coverage, err := readBedRegions(coverageFile)
+	if err != nil {
+		return err
+	}
+	meanCoverage, err := readMeanCoverage(coverage)
+	if err != nil {
+		return fmt.Errorf("cannot read coverage file '%s': %w", coverageFile, err)
+	}
+
+	// Use the only lab datum with targeted regions or the only one matching the coverage file
+	var matching []int
+	for idx, regions := range targets {
+		if len(targets) == 1 || missingRegions(regions, meanCoverage) == 0 {
+			matching = append(matching, idx)
+		}
+	}
+	if len(matching) != 1 {
+		return fmt.Errorf("cannot assign coverage file '%s' to one of %d lab data with targeted regions", coverageFile, len(targets))
+	}
+
+	labData := &data.Donors[0].LabData[matching[0]]
+	if labData.SequenceData.MinCoverage <= 0 {
+		return fmt.Errorf("targeted regions above minimum coverage requires minCoverage")
+	}
+	fraction, err := targetedRegionsAboveMinCoverage(targets[matching[0]], meanCoverage, labData.SequenceData.MinCoverage)
+	if err != nil {
+		return fmt.Errorf("cannot read coverage file '%s': %w", coverageFile, err)
+	}
+	labData.SequenceData.TargetedRegionsAboveMinCoverage = fraction
+	return nil
+}
+
+// addBedFile adds the BED file, relative to filesDir, to the files of the lab datum replacing an existing BED file
+func addBedFile(labData *metadata.LabDatum, bedFile string, filesDir string) error {
+	if labData.SequenceData == nil {
+		labData.SequenceData = &metadata.SequenceData{Files: []metadata.File{}}
+	}
+
+	path := filepath.Join(filesDir, bedFile)
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("cannot read targeted regions: %w", err)
+	}
+	checksum, err := sha256File(path)
+	if err != nil {
+		return fmt.Errorf("cannot read targeted regions: %w", err)
+	}
+	checksumType := metadata.Sha256
+	file := metadata.File{
+		ChecksumType:    &checksumType,
+		FileChecksum:    checksum,
+		FilePath:        bedFile,
+		FileSizeInBytes: float64(info.Size()),
+		FileType:        metadata.Bed,
+	}
+
+	// Only one BED file is allowed
+	files := []metadata.File{}
+	for _, existing := range labData.SequenceData.Files {
+		if existing.FileType != metadata.Bed {
+			files = append(files, existing)
+		}
+	}
+	labData.SequenceData.Files = append(files, file)
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+func writeTestFile(t *testing.T, dir string, name string, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func targetedMetadata() *metadata.Metadata {
+	return &metadata.Metadata{
+		Donors: []metadata.Donor{
+			{
+				LabData: []metadata.LabDatum{
+					{SequenceType: metadata.Dna, LibraryType: metadata.Panel, SequenceData: &metadata.SequenceData{MinCoverage: 100}},
+					{SequenceType: metadata.Rna, LibraryType: metadata.Panel, SequenceData: &metadata.SequenceData{MinCoverage: 50}},
+					{SequenceType: metadata.Dna, LibraryType: metadata.Wgs},
+				},
+			},
+		},
+	}
+}
+
+func TestAddTargetedRegionsPerLabDatum(t *testing.T) {
+	filesDir := t.TempDir()
+	writeTestFile(t, filesDir, "dna.bed", "chr1\t100\t200\nchr1\t300\t400\n")
+	writeTestFile(t, filesDir, "rna.bed", "chr2\t100\t200\n")
+	writeTestFile(t, filesDir, "sample.regions.bed", "chr2\t100\t200\t60.5\n")
+	profile := &Profile{SequenceType: "dna", TargetedRegionsFile: "dna.bed", Rna: &Profile{SequenceType: "rna", TargetedRegionsFile: "rna.bed"}}
+
+	data := targetedMetadata()
+	if err := addTargetedRegions(data, profile, filesDir, filepath.Join(filesDir, "sample.regions.bed")); err != nil {
+		t.Fatal(err)
+	}
+
+	labData := data.Donors[0].LabData
+	for idx, expected := range []string{"dna.bed", "rna.bed"} {
+		if files := labData[idx].SequenceData.Files; len(files) != 1 || files[0].FilePath != expected {
+			t.Errorf("expected BED file '%s' for lab data %d, got %v", expected, idx, files)
+		}
+	}
+	if labData[2].SequenceData != nil {
+		t.Errorf("expected no BED file for WGS lab data, got %v", labData[2].SequenceData.Files)
+	}
+	if labData[0].SequenceData.TargetedRegionsAboveMinCoverage != 0 || labData[1].SequenceData.TargetedRegionsAboveMinCoverage != 1 {
+		t.Errorf("expected coverage of RNA lab data only, got %g and %g", labData[0].SequenceData.TargetedRegionsAboveMinCoverage, labData[1].SequenceData.TargetedRegionsAboveMinCoverage)
+	}
+}
+
+func TestAddTargetedRegionsWithAmbiguousCoverage(t *testing.T) {
+	filesDir := t.TempDir()
+	writeTestFile(t, filesDir, "panel.bed", "chr1\t100\t200\n")
+	writeTestFile(t, filesDir, "sample.regions.bed", "chr1\t100\t200\t60.5\n")
+	profile := &Profile{SequenceType: "dna", TargetedRegionsFile: "panel.bed", Rna: &Profile{SequenceType: "rna", TargetedRegionsFile: "panel.bed"}}
+
+	if err := addTargetedRegions(targetedMetadata(), profile, filesDir, filepath.Join(filesDir, "sample.regions.bed")); err == nil {
+		t.Error("expected error for coverage matching multiple lab data")
+	}
+}
@@ -0,0 +1,87 @@
+-- Minimal Onkostar schema containing all tables and columns used by os2grzmeta queries
+
+CREATE TABLE patient (
+    id                         INT PRIMARY KEY,
+    patienten_id               VARCHAR(64),
+    geschlecht                 VARCHAR(1)
+);
+
+CREATE TABLE prozedur (
+    id                         INT PRIMARY KEY,
+    patient_id                 INT,
+    hauptprozedur_id           INT
+);
+
+CREATE TABLE organisationunit (
+    id                         INT PRIMARY KEY,
+    identifier                 VARCHAR(255)
+);
+
+CREATE TABLE property_catalogue_version_entry (
+    id                         INT PRIMARY KEY,
+    property_version_id        INT,
+    code                       VARCHAR(64),
+    shortdesc                  VARCHAR(255)
+);
+
+CREATE TABLE dk_molekulargenetik (
+    id                             INT PRIMARY KEY,
+    einsendenummer                 VARCHAR(64),
+    entnahmedatum                  DATE,
+    materialfixierung              VARCHAR(8),
+    artdersequenzierung            VARCHAR(64),
+    tumorzellgehalt                VARCHAR(16),
+    referenzgenom                  VARCHAR(16),
+    panel                          VARCHAR(255),
+    kostentraegertyp               VARCHAR(8),
+    durchfuehrendeoe_fachabteilung INT,
+    nukleinsaeure                  VARCHAR(64),
+    nukleinsaeure_propcat_version  INT,
+    probenmaterial                 VARCHAR(64),
+    probenmaterial_propcat_version INT
+);
+
+CREATE TABLE dk_diagnose (
+    id                         INT PRIMARY KEY,
+    diagnosedatum              DATE,
+    icdo3_lokalisation         VARCHAR(16)
+);
+
+CREATE TABLE dk_dnpm_kpa (
+    id                         INT PRIMARY KEY,
+    fallnummermv               VARCHAR(64),
+    consentmv64e               INT
+);
+
+CREATE TABLE dk_dnpm_therapieplan (
+    id                         INT PRIMARY KEY,
+    ref_dnpm_klinikanamnese    INT
+);
+
+CREATE TABLE dk_dnpm_uf_rebiopsie (
+    id                         INT PRIMARY KEY,
+    ref_molekulargenetik       INT
+);
+
+CREATE TABLE dk_dnpm_uf_reevaluation (
+    id                         INT PRIMARY KEY,
+    ref_molekulargenetik       INT
+);
+
+CREATE TABLE dk_dnpm_uf_einzelempfehlung (
+    id                         INT PRIMARY KEY,
+    ref_molekulargenetik       INT
+);
+
+CREATE TABLE dk_dnpm_consentmv (
+    id                         INT PRIMARY KEY
+);
+
+CREATE TABLE dk_dnpm_uf_consentmvverlauf (
+    id                         INT PRIMARY KEY,
+    date                       DATE,
+    version                    VARCHAR(255),
+    sequencing                 VARCHAR(16),
+    caseidentification         VARCHAR(16),
+    reidentification           VARCHAR(16)
+);
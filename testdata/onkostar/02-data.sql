@@ -0,0 +1,46 @@
+-- Anonymised sample data: one patient with one sequenced sample linked to a therapy plan and MV consent
+
+INSERT INTO patient (id, patienten_id, geschlecht) VALUES (1, 'P000001', 'w');
+
+INSERT INTO organisationunit (id, identifier) VALUES (1, 'PATHO');
+
+INSERT INTO property_catalogue_version_entry (id, property_version_id, code, shortdesc) VALUES
+    (1, 1, 'DNA', 'DNA'),
+    (2, 1, 'RNA', 'RNA'),
+    (3, 2, 'T', 'Tumorgewebe'),
+    (4, 2, 'B', 'Blut');
+
+-- Molekulargenetische Untersuchung
+INSERT INTO prozedur (id, patient_id, hauptprozedur_id) VALUES (10, 1, NULL);
+INSERT INTO dk_molekulargenetik (id, einsendenummer, entnahmedatum, materialfixierung, artdersequenzierung,
+                                 tumorzellgehalt, referenzgenom, panel, kostentraegertyp,
+                                 durchfuehrendeoe_fachabteilung, nukleinsaeure, nukleinsaeure_propcat_version,
+                                 probenmaterial, probenmaterial_propcat_version)
+VALUES (10, 'H/2025/0001', '2025-03-12', '3', 'PanelKit', '40', 'HG19', 'OCAplus', 'GKV', 1, 'DNA', 1, 'T', 2);
+
+-- RNA sequenced from the same sample
+INSERT INTO prozedur (id, patient_id, hauptprozedur_id) VALUES (11, 1, NULL);
+INSERT INTO dk_molekulargenetik (id, einsendenummer, entnahmedatum, materialfixierung, artdersequenzierung,
+                                 tumorzellgehalt, referenzgenom, panel, kostentraegertyp,
+                                 durchfuehrendeoe_fachabteilung, nukleinsaeure, nukleinsaeure_propcat_version,
+                                 probenmaterial, probenmaterial_propcat_version)
+VALUES (11, 'H/2025/0001', '2025-03-12', '3', 'PanelKit', '40', 'HG19', 'OCAplus', 'GKV', 1, 'RNA', 1, 'T', 2);
+
+-- Diagnose with ICD-O-3 topography
+INSERT INTO prozedur (id, patient_id, hauptprozedur_id) VALUES (5, 1, NULL);
+INSERT INTO dk_diagnose (id, diagnosedatum, icdo3_lokalisation) VALUES (5, '2025-01-15', 'C34.1');
+
+-- DNPM Klinik/Anamnese with MV consent
+INSERT INTO prozedur (id, patient_id, hauptprozedur_id) VALUES (20, 1, NULL);
+INSERT INTO dk_dnpm_kpa (id, fallnummermv, consentmv64e) VALUES (20, 'FALL-2025-0001', 21);
+INSERT INTO prozedur (id, patient_id, hauptprozedur_id) VALUES (21, 1, NULL);
+INSERT INTO dk_dnpm_consentmv (id) VALUES (21);
+INSERT INTO prozedur (id, patient_id, hauptprozedur_id) VALUES (22, 1, 21);
+INSERT INTO dk_dnpm_uf_consentmvverlauf (id, date, version, sequencing, caseidentification, reidentification)
+VALUES (22, '2025-02-20', 'Patienteninformation und Einwilligung MVGenomSeq vers01', 'permit', 'deny', 'permit');
+
+-- DNPM Therapieplan with Einzelempfehlung referencing the Molekulargenetische Untersuchung
+INSERT INTO prozedur (id, patient_id, hauptprozedur_id) VALUES (30, 1, NULL);
+INSERT INTO dk_dnpm_therapieplan (id, ref_dnpm_klinikanamnese) VALUES (30, 20);
+INSERT INTO prozedur (id, patient_id, hauptprozedur_id) VALUES (31, 1, 30);
+INSERT INTO dk_dnpm_uf_einzelempfehlung (id, ref_molekulargenetik) VALUES (31, 10);
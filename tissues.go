@@ -0,0 +1,84 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// Tissue is an entry of the tissue ontology
+type Tissue struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// TissueMapping maps Onkostar Probenmaterial codes and ICD-O-3 topography codes to tissue ontology entries
+type TissueMapping struct {
+	Ontology metadata.TissueOntology `json:"ontology"`
+	// Probenmaterial is used first, e.g. for blood samples independent of the tumor localisation
+	Probenmaterial map[string]Tissue `json:"probenmaterial"`
+	// Tumor contains Probenmaterial codes of tumor material, only these use the topography of the diagnosis
+	Tumor []string `json:"tumor"`
+	// Topography contains ICD-O-3 topography codes with three characters or with subcategory, e.g. 'C42.1'
+	Topography map[string]Tissue `json:"topography"`
+}
+
+//go:embed tissues.json
+var tissues []byte
+
+// ReadTissueMapping returns the tissue mapping.
+// The given file, if any, replaces the default mapping.
+func ReadTissueMapping(filename string) (TissueMapping, error) {
+	result := TissueMapping{}
+	if len(filename) == 0 {
+		err := json.Unmarshal(tissues, &result)
+		return result, err
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return result, fmt.Errorf("cannot read tissues: %w", err)
+	}
+	if err := json.Unmarshal(content, &result); err != nil {
+		return result, fmt.Errorf("cannot read tissues '%s': %w", filename, err)
+	}
+	return result, nil
+}
+
+// Find returns the tissue for the Probenmaterial code or, for tumor material only, the ICD-O-3 topography code
+func (mapping TissueMapping) Find(probenmaterial string, topography string) *Tissue {
+	if tissue, ok := mapping.Probenmaterial[probenmaterial]; ok {
+		return &tissue
+	}
+	if !slices.Contains(mapping.Tumor, probenmaterial) {
+		return nil
+	}
+
+	code := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(topography), ".", ""))
+	if len(code) < 3 {
+		return nil
+	}
+	if len(code) > 3 {
+		if tissue, ok := mapping.Topography[code[:3]+"."+code[3:4]]; ok {
+			return &tissue
+		}
+	}
+	if tissue, ok := mapping.Topography[code[:3]]; ok {
+		return &tissue
+	}
+	return nil
+}
+
+// applyTissue sets tissue ontology and tissue type of the lab datum
+func applyTissue(labDatum *metadata.LabDatum, mapping TissueMapping, tissue *Tissue) {
+	if tissue == nil {
+		return
+	}
+	labDatum.TissueOntology = mapping.Ontology
+	labDatum.TissueTypeID = tissue.ID
+	labDatum.TissueTypeName = tissue.Name
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestTissueMappingFind(t *testing.T) {
+	mapping, err := ReadTissueMapping("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		probenmaterial string
+		topography     string
+		expected       string
+	}{
+		{"tumor with subcategory", "T", "C42.1", "UBERON:0002371"},
+		{"tumor with category", "T", "C34.1", "UBERON:0002048"},
+		{"tumor without topography", "T", "", ""},
+		{"blood ignores topography", "B", "C34.1", "UBERON:0000178"},
+		{"unknown material ignores topography", "X", "C34.1", ""},
+		{"missing material ignores topography", "", "C34.1", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tissue := mapping.Find(test.probenmaterial, test.topography)
+			if len(test.expected) == 0 {
+				if tissue != nil {
+					t.Errorf("expected no tissue, got '%s'", tissue.ID)
+				}
+				return
+			}
+			if tissue == nil || tissue.ID != test.expected {
+				t.Errorf("expected tissue '%s', got %v", test.expected, tissue)
+			}
+		})
+	}
+}
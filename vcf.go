@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// VcfHeader contains information about the pipeline found in VCF meta-information lines
+type VcfHeader struct {
+	Callers         []metadata.CallerUsed
+	PipelineName    string
+	PipelineVersion string
+	ReferenceGenome metadata.ReferenceGenome
+	// Contigs contains the length of each contig declared in '##contig' lines
+	Contigs map[string]int64
+}
+
+// openFile opens a plain or gzip/BGZF compressed file
+func openFile(filename string) (io.ReadCloser, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(f)
+	if magic, err := reader.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{gz, f}, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{reader, f}, nil
+}
+
+var (
+	vcfStructuredValue = regexp.MustCompile(`(\w+)=("[^"]*"|[^,>]*)`)
+	dragenVersion      = regexp.MustCompile(`SW: ([^,"]+)`)
+)
+
+// structuredValues returns the values of a structured meta-information line like '<ID=Mutect2,Version="4.2.6.1">'
+func structuredValues(value string) map[string]string {
+	result := map[string]string{}
+	for _, match := range vcfStructuredValue.FindAllStringSubmatch(value, -1) {
+		if _, ok := result[match[1]]; !ok {
+			result[match[1]] = strings.Trim(match[2], "\"")
+		}
+	}
+	return result
+}
+
+// referenceGenomeOf returns the reference genome for names like 'hg19', 'hs37d5' or 'GRCh38', if known
+func referenceGenomeOf(name string) metadata.ReferenceGenome {
+	name = strings.ToLower(name)
+	// GATK resource bundles use 'Homo_sapiens_assembly38' and 'Homo_sapiens_assembly19' (b37)
+	for _, alias := range []string{"grch38", "hg38", "hs38", "assembly38"} {
+		if strings.Contains(name, alias) {
+			return metadata.GRCh38
+		}
+	}
+	for _, alias := range []string{"grch37", "hg19", "hs37", "b37", "human_g1k_v37", "assembly19"} {
+		if strings.Contains(name, alias) {
+			return metadata.GRCh37
+		}
+	}
+	return ""
+}
+
+// hasCaller returns true if a caller with the given name was found
+func (header *VcfHeader) hasCaller(name string) bool {
+	return slices.ContainsFunc(header.Callers, func(caller metadata.CallerUsed) bool {
+		return caller.Name == name
+	})
+}
+
+// readVcfHeader reads the meta-information lines of a plain or compressed VCF file.
+// Supported are '##source', GATK command lines as well as DRAGEN and Ion Reporter version lines.
+func readVcfHeader(filename string) (*VcfHeader, error) {
+	f, err := openFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result := VcfHeader{Contigs: map[string]int64{}}
+	addCaller := func(name string, version string) {
+		caller := metadata.CallerUsed{Name: name, Version: version}
+		if len(name) > 0 && !slices.Contains(result.Callers, caller) {
+			result.Callers = append(result.Callers, caller)
+		}
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	sourceVersion := ""
+	var sources []string
+	for lineNumber := 0; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if lineNumber == 0 && !strings.HasPrefix(line, "##fileformat=VCF") {
+			return nil, fmt.Errorf("file '%s' is not a VCF file", filename)
+		}
+		if !strings.HasPrefix(line, "##") {
+			break
+		}
+
+		key, value, _ := strings.Cut(strings.TrimPrefix(line, "##"), "=")
+		switch {
+		case key == "source":
+			sources = append(sources, strings.Trim(value, "\""))
+		case key == "source_version":
+			sourceVersion = strings.Trim(value, "\"")
+		case key == "reference":
+			result.ReferenceGenome = referenceGenomeOf(value)
+		case key == "contig":
+			values := structuredValues(value)
+			length, _ := strconv.ParseInt(values["length"], 10, 64)
+			result.Contigs[values["ID"]] = length
+		case key == "GATKCommandLine" || strings.HasPrefix(key, "GATKCommandLine."):
+			values := structuredValues(value)
+			addCaller(values["ID"], values["Version"])
+		case key == "DRAGENVersion":
+			if match := dragenVersion.FindStringSubmatch(value); match != nil {
+				result.PipelineName = "DRAGEN"
+				result.PipelineVersion = strings.TrimSpace(match[1])
+			}
+		case key == "IonReporterSoftwareVersion":
+			result.PipelineName = "Ion Reporter"
+			result.PipelineVersion = strings.Trim(value, "\"")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Sources like 'tvc 5.12-27' contain the version, others like 'strelka' use '##source_version'.
+	// Sources without version, like 'Mutect2' or 'DRAGEN_SNV', are already known from GATK or DRAGEN lines.
+	for _, source := range sources {
+		name, version, _ := strings.Cut(source, " ")
+		if fields := strings.Fields(version); len(fields) > 0 {
+			version = fields[0]
+		} else {
+			version = sourceVersion
+		}
+		if len(version) == 0 && (result.PipelineName == "DRAGEN" && strings.HasPrefix(name, "DRAGEN") || result.hasCaller(name)) {
+			continue
+		}
+		addCaller(name, version)
+	}
+	if result.PipelineName == "DRAGEN" {
+		addCaller("DRAGEN", result.PipelineVersion)
+	}
+
+	return &result, nil
+}
+
+// applyVcfHeaders sets callers, pipeline and reference genome of each lab datum using the headers of its VCF files
+// in filesDir. Values contradicting the profile or Onkostar are reported, values found in VCF files are used.
+// The reference genome from Onkostar is not replaced.
+func applyVcfHeaders(data *metadata.Metadata, filesDir string) {
+	if len(data.Donors) == 0 {
+		return
+	}
+	for idx := range data.Donors[0].LabData {
+		sequenceData := data.Donors[0].LabData[idx].SequenceData
+		if sequenceData == nil {
+			continue
+		}
+
+		var callers []metadata.CallerUsed
+		for _, file := range sequenceData.Files {
+			if file.FileType != metadata.Vcf {
+				continue
+			}
+			header, err := readVcfHeader(filepath.Join(filesDir, file.FilePath))
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			} else if err != nil {
+				log.Printf("Warning: Cannot read VCF header: %s\n", err.Error())
+				continue
+			}
+
+			for _, caller := range header.Callers {
+				if !slices.Contains(callers, caller) {
+					callers = append(callers, caller)
+				}
+			}
+			if len(header.PipelineName) > 0 {
+				if len(sequenceData.BioinformaticsPipelineName) > 0 && (sequenceData.BioinformaticsPipelineName != header.PipelineName || sequenceData.BioinformaticsPipelineVersion != header.PipelineVersion) {
+					log.Printf("Warning: Pipeline '%s %s' in '%s' differs from '%s %s'\n", header.PipelineName, header.PipelineVersion, file.FilePath, sequenceData.BioinformaticsPipelineName, sequenceData.BioinformaticsPipelineVersion)
+				}
+				sequenceData.BioinformaticsPipelineName = header.PipelineName
+				sequenceData.BioinformaticsPipelineVersion = header.PipelineVersion
+			}
+			if len(header.ReferenceGenome) > 0 {
+				if len(sequenceData.ReferenceGenome) == 0 {
+					sequenceData.ReferenceGenome = header.ReferenceGenome
+				} else if sequenceData.ReferenceGenome != header.ReferenceGenome {
+					log.Printf("Warning: Reference genome '%s' in '%s' differs from '%s' in Onkostar\n", header.ReferenceGenome, file.FilePath, sequenceData.ReferenceGenome)
+				}
+			}
+		}
+
+		if len(callers) == 0 {
+			continue
+		}
+		for _, caller := range sequenceData.CallerUsed {
+			if len(caller.Name) > 0 && !slices.Contains(callers, caller) {
+				log.Printf("Warning: Caller '%s %s' not found in VCF files of lab data %d\n", caller.Name, caller.Version, idx)
+			}
+		}
+		sequenceData.CallerUsed = callers
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+// captureLog returns the log output written during the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &output
+}
+
+const vcfColumns = "#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\n"
+
+func TestReadVcfHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		gzipped  bool
+		expected VcfHeader
+	}{
+		{
+			name: "GATK",
+			header: "##fileformat=VCFv4.2\n" +
+				"##GATKCommandLine=<ID=Mutect2,CommandLine=\"Mutect2 --output tumor.vcf.gz --reference /ref/Homo_sapiens_assembly38.fasta\",Version=\"4.2.6.1\",Date=\"April 2, 2025 10:12:01 AM CEST\">\n" +
+				"##GATKCommandLine.FilterMutectCalls=<ID=FilterMutectCalls,CommandLine=\"FilterMutectCalls --variant tumor.vcf.gz\",Version=\"4.2.6.1\",Date=\"April 2, 2025 10:42:17 AM CEST\">\n" +
+				"##contig=<ID=chr1,length=248956422>\n" +
+				"##reference=file:///ref/Homo_sapiens_assembly38.fasta\n" +
+				"##source=Mutect2\n" +
+				"##source=FilterMutectCalls\n",
+			gzipped: true,
+			expected: VcfHeader{
+				Callers:         []metadata.CallerUsed{{Name: "Mutect2", Version: "4.2.6.1"}, {Name: "FilterMutectCalls", Version: "4.2.6.1"}},
+				ReferenceGenome: metadata.GRCh38,
+				Contigs:         map[string]int64{"chr1": 248956422},
+			},
+		},
+		{
+			name: "DRAGEN",
+			header: "##fileformat=VCFv4.2\n" +
+				"##DRAGENVersion=<ID=dragen,Version=\"SW: 07.021.624.3.10.4, HW: 07.021.624\">\n" +
+				"##DRAGENCommandLine=<ID=dragen,Date=\"Wed Apr 02 10:12:01 CEST 2025\",CommandLineOptions=\"--enable-variant-caller true\">\n" +
+				"##source=DRAGEN_SNV\n" +
+				"##reference=file:///staging/human/reference/hg38_alt_masked_graph_v2/hg38.fa\n",
+			expected: VcfHeader{
+				Callers:         []metadata.CallerUsed{{Name: "DRAGEN", Version: "07.021.624.3.10.4"}},
+				PipelineName:    "DRAGEN",
+				PipelineVersion: "07.021.624.3.10.4",
+				ReferenceGenome: metadata.GRCh38,
+				Contigs:         map[string]int64{},
+			},
+		},
+		{
+			name: "strelka",
+			header: "##fileformat=VCFv4.1\n" +
+				"##source=strelka\n" +
+				"##source_version=2.9.10\n" +
+				"##reference=file:///ref/hs37d5.fa\n" +
+				"##contig=<ID=1,length=249250621>\n",
+			gzipped: true,
+			expected: VcfHeader{
+				Callers:         []metadata.CallerUsed{{Name: "strelka", Version: "2.9.10"}},
+				ReferenceGenome: metadata.GRCh37,
+				Contigs:         map[string]int64{"1": 249250621},
+			},
+		},
+		{
+			name: "Ion Reporter",
+			header: "##fileformat=VCFv4.1\n" +
+				"##source=\"tvc 5.12-27 (7e5d5e6) - Torrent Variant Caller\"\n" +
+				"##IonReporterSoftwareVersion=\"5.18.4.0\"\n" +
+				"##reference=hg19\n",
+			expected: VcfHeader{
+				Callers:         []metadata.CallerUsed{{Name: "tvc", Version: "5.12-27"}},
+				PipelineName:    "Ion Reporter",
+				PipelineVersion: "5.18.4.0",
+				ReferenceGenome: metadata.GRCh37,
+				Contigs:         map[string]int64{},
+			},
+		},
+		{
+			name:     "no meta-information",
+			header:   "##fileformat=VCFv4.2\n",
+			expected: VcfHeader{Contigs: map[string]int64{}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			content := []byte(test.header + vcfColumns + "chr1\t100\t.\tA\tT\t.\tPASS\t.\n")
+			if test.gzipped {
+				var compressed bytes.Buffer
+				gz := gzip.NewWriter(&compressed)
+				_, _ = gz.Write(content)
+				_ = gz.Close()
+				content = compressed.Bytes()
+			}
+			filename := filepath.Join(t.TempDir(), "sample.vcf.gz")
+			if err := os.WriteFile(filename, content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			actual, err := readVcfHeader(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*actual, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, *actual)
+			}
+		})
+	}
+}
+
+func TestReadVcfHeaderOfInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "sample.vcf", "chr1\t100\t.\tA\tT\t.\tPASS\t.\n")
+	if _, err := readVcfHeader(filepath.Join(dir, "sample.vcf")); err == nil || !strings.Contains(err.Error(), "is not a VCF file") {
+		t.Errorf("expected error for file without fileformat line, got %v", err)
+	}
+}
+
+func TestReferenceGenomeOf(t *testing.T) {
+	tests := map[string]metadata.ReferenceGenome{
+		"hs37":                          metadata.GRCh37,
+		"hs37d5":                        metadata.GRCh37,
+		"file:///ref/hs37d5.fa.gz":      metadata.GRCh37,
+		"human_g1k_v37.fasta":           metadata.GRCh37,
+		"Homo_sapiens_assembly19.fasta": metadata.GRCh37,
+		"hg19":                          metadata.GRCh37,
+		"GRCh38":                        metadata.GRCh38,
+		"hs38DH":                        metadata.GRCh38,
+		"Homo_sapiens_assembly38.fasta": metadata.GRCh38,
+		"T2T-CHM13":                     "",
+	}
+	for name, expected := range tests {
+		if actual := referenceGenomeOf(name); actual != expected {
+			t.Errorf("expected reference genome '%s' for '%s', got '%s'", expected, name, actual)
+		}
+	}
+}
+
+func TestApplyVcfHeaders(t *testing.T) {
+	filesDir := t.TempDir()
+	writeTestFile(t, filesDir, "tumor.vcf", "##fileformat=VCFv4.1\n"+
+		"##source=\"tvc 5.12-27 (7e5d5e6) - Torrent Variant Caller\"\n"+
+		"##IonReporterSoftwareVersion=\"5.18.4.0\"\n"+
+		"##reference=GRCh38\n"+vcfColumns)
+
+	tests := []struct {
+		name         string
+		sequenceData metadata.SequenceData
+		expected     metadata.SequenceData
+		warnings     []string
+	}{
+		{
+			name: "values from profile and Onkostar",
+			sequenceData: metadata.SequenceData{
+				BioinformaticsPipelineName:    "Ion reporter",
+				BioinformaticsPipelineVersion: "5,2",
+				CallerUsed:                    []metadata.CallerUsed{{Name: "Ion reporter", Version: "5.2"}},
+				ReferenceGenome:               metadata.GRCh37,
+			},
+			expected: metadata.SequenceData{
+				BioinformaticsPipelineName:    "Ion Reporter",
+				BioinformaticsPipelineVersion: "5.18.4.0",
+				CallerUsed:                    []metadata.CallerUsed{{Name: "tvc", Version: "5.12-27"}},
+				ReferenceGenome:               metadata.GRCh37,
+			},
+			warnings: []string{
+				"Pipeline 'Ion Reporter 5.18.4.0' in 'tumor.vcf' differs from 'Ion reporter 5,2'",
+				"Reference genome 'GRCh38' in 'tumor.vcf' differs from 'GRCh37' in Onkostar",
+				"Caller 'Ion reporter 5.2' not found in VCF files of lab data 0",
+			},
+		},
+		{
+			name: "matching values",
+			sequenceData: metadata.SequenceData{
+				BioinformaticsPipelineName:    "Ion Reporter",
+				BioinformaticsPipelineVersion: "5.18.4.0",
+				CallerUsed:                    []metadata.CallerUsed{{Name: "tvc", Version: "5.12-27"}},
+				ReferenceGenome:               metadata.GRCh38,
+			},
+			expected: metadata.SequenceData{
+				BioinformaticsPipelineName:    "Ion Reporter",
+				BioinformaticsPipelineVersion: "5.18.4.0",
+				CallerUsed:                    []metadata.CallerUsed{{Name: "tvc", Version: "5.12-27"}},
+				ReferenceGenome:               metadata.GRCh38,
+			},
+		},
+		{
+			name:         "missing values",
+			sequenceData: metadata.SequenceData{},
+			expected: metadata.SequenceData{
+				BioinformaticsPipelineName:    "Ion Reporter",
+				BioinformaticsPipelineVersion: "5.18.4.0",
+				CallerUsed:                    []metadata.CallerUsed{{Name: "tvc", Version: "5.12-27"}},
+				ReferenceGenome:               metadata.GRCh38,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := captureLog(t)
+			files := []metadata.File{
+				{FilePath: "tumor.vcf", FileType: metadata.Vcf},
+				{FilePath: "missing.vcf", FileType: metadata.Vcf},
+			}
+			test.sequenceData.Files = files
+			test.expected.Files = files
+			data := &metadata.Metadata{
+				Donors: []metadata.Donor{{LabData: []metadata.LabDatum{{SequenceData: &test.sequenceData}}}},
+			}
+
+			applyVcfHeaders(data, filesDir)
+
+			if actual := data.Donors[0].LabData[0].SequenceData; !reflect.DeepEqual(*actual, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, *actual)
+			}
+			if count := strings.Count(output.String(), "Warning:"); count != len(test.warnings) {
+				t.Errorf("expected %d warnings, got %q", len(test.warnings), output.String())
+			}
+			for _, warning := range test.warnings {
+				if !strings.Contains(output.String(), warning) {
+					t.Errorf("expected warning '%s', got %q", warning, output.String())
+				}
+			}
+		})
+	}
+}
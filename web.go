@@ -0,0 +1,116 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+//go:embed web/index.html
+var indexHtml []byte
+
+type WebCmd struct {
+	Listen string `help:"Address to listen on" default:"localhost:8080"`
+}
+
+func (cmd *WebCmd) Run(globals *Globals, repository Repository) error {
+	server := &webServer{globals: globals, repository: repository}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", handleIndex)
+	mux.HandleFunc("GET /api/options", handleOptions)
+	mux.HandleFunc("GET /api/fallnummern", server.handleFallnummern)
+	mux.HandleFunc("POST /api/metadata", server.handleMetadata)
+
+	log.Printf("Web frontend available at http://%s/\n", cmd.Listen)
+	return http.ListenAndServe(cmd.Listen, mux)
+}
+
+type webServer struct {
+	globals    *Globals
+	repository Repository
+}
+
+func handleIndex(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(indexHtml)
+}
+
+func handleOptions(w http.ResponseWriter, _ *http.Request) {
+	writeJson(w, http.StatusOK, map[string]any{
+		"kliniken": ReadProfiles(),
+		"grz":      genomicDataCenters,
+		"kdk":      clinicalDataNodes,
+	})
+}
+
+func (server *webServer) handleFallnummern(w http.ResponseWriter, r *http.Request) {
+	fallnummern, err := server.repository.FetchFallnummern(r.URL.Query().Get("sampleId"))
+	if err != nil {
+		log.Printf("Cannot fetch Fallnummern: %s\n", err.Error())
+		writeJson(w, http.StatusInternalServerError, []string{"Fallnummern konnten nicht ermittelt werden"})
+		return
+	}
+	if fallnummern == nil {
+		fallnummern = []string{}
+	}
+	writeJson(w, http.StatusOK, fallnummern)
+}
+
+func (server *webServer) handleMetadata(w http.ResponseWriter, r *http.Request) {
+	var request MetadataRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJson(w, http.StatusBadRequest, []string{"Ungültige Anfrage"})
+		return
+	}
+
+	if messages := validateRequest(request); len(messages) > 0 {
+		writeJson(w, http.StatusUnprocessableEntity, messages)
+		return
+	}
+
+	data, err := createMetadata(server.repository, request)
+	if err != nil {
+		log.Printf("Cannot fetch metadata: %s\n", err.Error())
+		writeJson(w, http.StatusUnprocessableEntity, []string{
+			fmt.Sprintf("Keine Daten für Einsendenummer '%s' gefunden", request.SampleId),
+		})
+		return
+	}
+
+	if r.URL.Query().Get("download") == "true" {
+		w.Header().Set("Content-Disposition", "attachment; filename=\"metadata.json\"")
+		addHistory(server.globals, newHistoryEntry(HistoryDownload, request, data, "web"))
+	}
+	writeJson(w, http.StatusOK, data)
+}
+
+// validateRequest returns a list of messages for all invalid or missing selections
+func validateRequest(request MetadataRequest) []string {
+	messages := []string{}
+	if len(request.SampleId) == 0 {
+		messages = append(messages, "Keine Einsendenummer angegeben")
+	}
+	if len(request.Ik) > 0 && FindKlinik(request.Ik) == nil {
+		messages = append(messages, fmt.Sprintf("Unbekannter Leistungserbringer '%s'", request.Ik))
+	}
+	if len(request.Profile) > 0 && FindProfile(request.Ik, request.Profile) == nil {
+		messages = append(messages, fmt.Sprintf("Profil '%s' ist für den Leistungserbringer nicht verfügbar", request.Profile))
+	}
+	if !hasOption(genomicDataCenters, request.Grz) {
+		messages = append(messages, "Kein gültiges Genomrechenzentrum ausgewählt")
+	}
+	if !hasOption(clinicalDataNodes, request.Kdk) {
+		messages = append(messages, "Kein gültiger klinischer Datenknoten ausgewählt")
+	}
+	return messages
+}
+
+func writeJson(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	j, _ := json.MarshalIndent(value, "", "  ")
+	_, _ = w.Write(j)
+}
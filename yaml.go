@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+	"gopkg.in/yaml.v3"
+)
+
+// isYamlFile returns true for files with extension '.yaml' or '.yml'
+func isYamlFile(filename string) bool {
+	extension := strings.ToLower(filepath.Ext(filename))
+	return extension == ".yaml" || extension == ".yml"
+}
+
+// jsonToYaml converts JSON content to YAML keeping the order of properties
+func jsonToYaml(content []byte) ([]byte, error) {
+	// YAML is a superset of JSON, so the JSON content can be read as YAML document
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+	resetStyle(&document)
+
+	var result bytes.Buffer
+	encoder := yaml.NewEncoder(&result)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return result.Bytes(), nil
+}
+
+// resetStyle removes JSON flow and quoting styles, strings are only quoted if required
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
+
+// yamlToJson converts YAML content to the canonical JSON representation of metadata.
+// Scalars are converted using the type of the target field, since hand-edited files may contain unquoted values
+// resolved as other YAML types, e.g. dates like 2025-03-12 or versions like 1.0.
+func yamlToJson(content []byte) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+	value, err := yamlValue(&document, reflect.TypeOf(metadata.Metadata{}))
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(value, "", "  ")
+}
+
+// yamlValue returns the value of the node for a target type, nil target types keep the YAML type of scalars
+func yamlValue(node *yaml.Node, target reflect.Type) (any, error) {
+	for target != nil && target.Kind() == reflect.Pointer {
+		target = target.Elem()
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return yamlValue(node.Content[0], target)
+	case yaml.AliasNode:
+		return yamlValue(node.Alias, target)
+	case yaml.MappingNode:
+		result := map[string]any{}
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			key := node.Content[idx].Value
+			value, err := yamlValue(node.Content[idx+1], fieldType(target, key))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			result[key] = value
+		}
+		return result, nil
+	case yaml.SequenceNode:
+		var itemType reflect.Type
+		if target != nil && target.Kind() == reflect.Slice {
+			itemType = target.Elem()
+		}
+		result := []any{}
+		for idx, item := range node.Content {
+			value, err := yamlValue(item, itemType)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", idx, err)
+			}
+			result = append(result, value)
+		}
+		return result, nil
+	}
+
+	if node.Tag == "!!null" {
+		return nil, nil
+	}
+	kind := reflect.Interface
+	if target != nil {
+		kind = target.Kind()
+	}
+	switch kind {
+	case reflect.String:
+		return node.Value, nil
+	case reflect.Float64, reflect.Int64:
+		f, err := strconv.ParseFloat(node.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", node.Value)
+		}
+		return f, nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(node.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean '%s'", node.Value)
+		}
+		return b, nil
+	}
+	// Unknown targets like the research consent scope keep dates as strings
+	if node.Tag == "!!timestamp" {
+		return node.Value, nil
+	}
+	var value any
+	err := node.Decode(&value)
+	return value, err
+}
+
+// fieldType returns the type of the struct field with the JSON property name or the element type of a map
+func fieldType(target reflect.Type, name string) reflect.Type {
+	if target == nil {
+		return nil
+	}
+	if target.Kind() == reflect.Map {
+		return target.Elem()
+	}
+	if target.Kind() != reflect.Struct {
+		return nil
+	}
+	for idx := range target.NumField() {
+		if property, _, _ := strings.Cut(target.Field(idx).Tag.Get("json"), ","); property == name {
+			return target.Field(idx).Type
+		}
+	}
+	return nil
+}
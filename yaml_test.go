@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pcvolkmer/mv64e-grz-dto-go"
+)
+
+func TestYamlRoundTrip(t *testing.T) {
+	data, err := createMetadata(fixtureRepository(t), MetadataRequest{SampleId: "H/2025/0001", Fallnummer: "FALL-2025-0001", Profile: "UKW - OCAplus (CCC-Patho)", Ik: "260960079"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := json.MarshalIndent(data, "", "  ")
+
+	content, err := jsonToYaml(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := yamlToJson(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := metadata.UnmarshalMetadata(actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j, _ := json.MarshalIndent(result, "", "  "); string(j) != string(expected) {
+		t.Errorf("expected unchanged metadata after round trip, got\n%s", j)
+	}
+}
+
+func TestYamlToJsonWithUnquotedValues(t *testing.T) {
+	content := `
+submission:
+  submissionDate: 2025-03-12
+  tanG: 0123
+donors:
+  - mvConsent:
+      version: 1.0
+      presentationDate: 2025-02-20
+      scope:
+        - type: permit
+          date: 2025-02-20
+          domain: mvSequencing
+    researchConsents:
+      - presentationDate: 2025-02-20
+        scope:
+          dateTime: 2025-02-20
+          count: 2
+    labData:
+      - sampleDate: 2025-03-12
+        kitName: 2.0
+        tumorCellCount:
+          - count: 40
+            method: pathology
+        sequenceData:
+          minCoverage: 30.5
+          nonCodingVariants: true
+`
+	j, err := yamlToJson([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := metadata.UnmarshalMetadata(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	donor := data.Donors[0]
+	labData := donor.LabData[0]
+	tests := []struct {
+		name     string
+		actual   any
+		expected any
+	}{
+		{"submissionDate", data.Submission.SubmissionDate, "2025-03-12"},
+		{"tanG", data.Submission.TanG, "0123"},
+		{"version", donor.MvConsent.Version, "1.0"},
+		{"presentationDate", *donor.MvConsent.PresentationDate, "2025-02-20"},
+		{"scope date", donor.MvConsent.Scope[0].Date, "2025-02-20"},
+		{"research consent scope", donor.ResearchConsents[0].Scope["dateTime"], "2025-02-20"},
+		{"research consent scope count", donor.ResearchConsents[0].Scope["count"], 2.0},
+		{"sampleDate", labData.SampleDate, "2025-03-12"},
+		{"kitName", labData.KitName, "2.0"},
+		{"tumorCellCount", labData.TumorCellCount[0].Count, 40.0},
+		{"minCoverage", labData.SequenceData.MinCoverage, 30.5},
+		{"nonCodingVariants", labData.SequenceData.NonCodingVariants, true},
+	}
+	for _, test := range tests {
+		if test.actual != test.expected {
+			t.Errorf("%s: expected %v (%T), got %v (%T)", test.name, test.expected, test.expected, test.actual, test.actual)
+		}
+	}
+}
+
+func TestYamlToJsonWithInvalidNumber(t *testing.T) {
+	if _, err := yamlToJson([]byte("donors:\n  - labData:\n      - tumorCellCount:\n          - count: viel\n")); err == nil {
+		t.Error("expected error for invalid number")
+	}
+}